@@ -0,0 +1,213 @@
+// Command admin manages users against the configured database. It reads the
+// same environment as the server, so inside the container it can be run as
+//
+//	echo "$PASSWORD" | ./admin create-admin -login root
+//	./admin set-role -login alice -role editor
+//	./admin list-users -role admin
+//
+// Passwords are read from the first line of stdin so that they do not end up
+// in the shell history or the process list.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"create-admin":   {"-login NAME, password from stdin", createAdmin},
+	"reset-password": {"-login NAME, password from stdin", resetPassword},
+	"set-role":       {"-login NAME -role ROLE", setRole},
+	"unlock":         {"-login NAME", unlock},
+	"list-users":     {"[-search TEXT] [-role ROLE]", listUsers},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := auth.LoadPasswordPolicy(); err != nil {
+		log.Fatalf("cannot load password policy: %v", err)
+	}
+	db.Init()
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatalf("%v: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin <command> [flags]")
+	for _, name := range []string{"create-admin", "reset-password", "set-role", "unlock", "list-users"} {
+		fmt.Fprintf(os.Stderr, "  %-15v %v\n", name, commands[name].usage)
+	}
+	os.Exit(2)
+}
+
+// createAdmin creates a user with the admin role. It is meant for the first
+// admin of a fresh deployment, existing users are promoted with set-role.
+func createAdmin(args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	login := flags.String("login", "", "login of the new admin")
+	flags.Parse(args)
+	if len(*login) == 0 || len(*login) > 100 {
+		return errors.New("login length should be at least 1 and no more than 100 characters")
+	}
+	user, err := db.Instance().GetUser(*login)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		return fmt.Errorf("user %v already exists, use set-role to promote it", *login)
+	}
+	hashedPass, err := readPassword()
+	if err != nil {
+		return err
+	}
+	err = db.Instance().AddUser(&models.User{Name: *login, Password: hashedPass, Role: constants.AdminRole})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("admin %v created\n", *login)
+	return nil
+}
+
+// resetPassword sets a new password and revokes refresh tokens of the user.
+func resetPassword(args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	login := flags.String("login", "", "login of the user")
+	flags.Parse(args)
+	user, err := findUser(*login)
+	if err != nil {
+		return err
+	}
+	hashedPass, err := readPassword()
+	if err != nil {
+		return err
+	}
+	err = db.Instance().SetUserPassword(user.ID, hashedPass)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("password of %v reset\n", *login)
+	return nil
+}
+
+func setRole(args []string) error {
+	flags := flag.NewFlagSet("set-role", flag.ExitOnError)
+	login := flags.String("login", "", "login of the user")
+	roleName := flags.String("role", "", "role to assign")
+	flags.Parse(args)
+	user, err := findUser(*login)
+	if err != nil {
+		return err
+	}
+	role, err := db.Instance().GetRole(*roleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return fmt.Errorf("role %q not found", *roleName)
+	}
+	err = db.Instance().SetUserRole(user.ID, role.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v is now %v\n", *login, role.Name)
+	return nil
+}
+
+// unlock lifts the lock put on the account after failed logins.
+func unlock(args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ExitOnError)
+	login := flags.String("login", "", "login of the user")
+	flags.Parse(args)
+	if _, err := findUser(*login); err != nil {
+		return err
+	}
+	err := auth.Unlock(*login)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v unlocked\n", *login)
+	return nil
+}
+
+func listUsers(args []string) error {
+	flags := flag.NewFlagSet("list-users", flag.ExitOnError)
+	search := flags.String("search", "", "fragment of the login")
+	role := flags.String("role", "", "role of the users")
+	flags.Parse(args)
+	users, err := db.Instance().GetUsers(&models.UsersFilter{Search: *search, Role: *role})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOGIN\tROLE\tCREATED\tSTATUS")
+	for _, user := range users {
+		status := "active"
+		switch {
+		case user.DisabledAt != nil:
+			status = "disabled"
+		case user.LockedUntil != nil && user.LockedUntil.After(time.Now()):
+			status = "locked"
+		case user.MustResetPassword:
+			status = "password reset required"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", user.Name, user.Role, user.CreatedAt.Format("02.01.2006"), status)
+	}
+	return w.Flush()
+}
+
+func findUser(login string) (*models.User, error) {
+	if login == "" {
+		return nil, errors.New("-login is required")
+	}
+	user, err := db.Instance().GetUser(login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %v not found", login)
+	}
+	return user, nil
+}
+
+// readPassword reads the password from the first line of stdin and returns
+// its hash.
+func readPassword() (string, error) {
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	pass := strings.TrimRight(line, "\r\n")
+	if err := auth.ValidatePassword(pass); err != nil {
+		return "", err
+	}
+	return auth.HashPassword(pass)
+}
@@ -2,15 +2,22 @@ package main
 
 import (
 	"errors"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/ffdb42/vk_trainee_task/docs"
 	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
 	"github.com/ffdb42/vk_trainee_task/internal/api/server"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
 	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/notify"
+	"github.com/ffdb42/vk_trainee_task/internal/storage"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
@@ -19,15 +26,86 @@ import (
 
 // @securityDefinitions.basic  BasicAuth
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Access токен из /auth/token в формате "Bearer <token>"
+
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API ключ из /api-key/, действует только на разрешенные его scopes ресурсы
+
 // @host      localhost:8888
 // @BasePath  /
 func main() {
 	db.Init()
 
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	store, err := storage.NewLocalStore(mediaDir, "/media/")
+	if err != nil {
+		log.Fatalf("cannot init media storage: %v", err)
+	}
+
+	retentionDays := constants.DefaultTrashRetentionDays
+	if env := os.Getenv("TRASH_RETENTION_DAYS"); env != "" {
+		retentionDays, err = strconv.Atoi(env)
+		if err != nil || retentionDays < 1 {
+			log.Fatalf("TRASH_RETENTION_DAYS should be a positive number of days")
+		}
+	}
+
+	err = auth.LoadPasswordPolicy()
+	if err != nil {
+		log.Fatalf("cannot load password policy: %v", err)
+	}
+
+	keys, activeKid, err := auth.ParseKeys(os.Getenv("JWT_KEYS"))
+	if err != nil {
+		log.Fatalf("cannot parse JWT_KEYS: %v", err)
+	}
+	if env := os.Getenv("JWT_ACTIVE_KID"); env != "" {
+		activeKid = env
+	}
+	err = auth.Init(keys, activeKid, constants.AccessTokenTTL)
+	if err != nil {
+		log.Fatalf("cannot init token signer: %v", err)
+	}
+	if len(keys) == 0 {
+		log.Printf("JWT_KEYS is not set, bearer tokens are disabled")
+	}
+
+	var notifier notify.Notifier = notify.NewWriterNotifier(os.Stdout)
+	if env := os.Getenv("NOTIFIER_FILE"); env != "" {
+		notifier, err = notify.NewFileNotifier(env)
+		if err != nil {
+			log.Fatalf("cannot init notifier: %v", err)
+		}
+	}
+
+	secureCookies := true
+	if env := os.Getenv("COOKIE_SECURE"); env != "" {
+		secureCookies, err = strconv.ParseBool(env)
+		if err != nil {
+			log.Fatalf("COOKIE_SECURE should be true or false")
+		}
+	}
+	if !secureCookies {
+		log.Printf("COOKIE_SECURE is false, session cookies are sent over plain HTTP")
+	}
+
+	expvar.Publish("credential_cache", expvar.Func(func() any { return auth.GetCredentialCacheStats() }))
+
 	mux := http.NewServeMux()
-	server := server.Server{}
+	srv := server.Server{Store: store, Notifier: notifier, InsecureCookies: !secureCookies}
 	port := os.Getenv("API_INT_PORT")
 
+	go srv.RunTrashRetention(time.Duration(retentionDays)*24*time.Hour, constants.TrashPurgeInterval)
+	go srv.RunAuthPurge(constants.AuthPurgeInterval)
+
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.RequestURI != "/" {
 			w.WriteHeader(http.StatusNotFound)
@@ -37,16 +115,33 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Hello world!"))
 	})
-	mux.HandleFunc("/sign-up/", server.SignUp)
-	mux.Handle("/actor/", middleware.Authenticate(http.HandlerFunc(server.ActorHandler)))
-	mux.Handle("/film/", middleware.Authenticate(http.HandlerFunc(server.FilmHandler)))
-	mux.Handle("/search/", middleware.Authenticate(http.HandlerFunc(server.SearchHandler)))
+	mux.HandleFunc("/sign-up/", srv.SignUp)
+	mux.HandleFunc("/auth/", srv.AuthHandler)
+	mux.Handle(constants.ChangePasswordPath, middleware.Authenticate(http.HandlerFunc(srv.ChangePassword)))
+	mux.Handle("/auth/logout", middleware.Authenticate(http.HandlerFunc(srv.Logout)))
+	mux.Handle("/me", middleware.Authenticate(http.HandlerFunc(srv.MeHandler)))
+	mux.Handle("/me/", middleware.Authenticate(http.HandlerFunc(srv.MeHandler)))
+	mux.Handle("/sessions/", middleware.Authenticate(http.HandlerFunc(srv.SessionHandler)))
+	mux.Handle("/actor/", middleware.Authenticate(middleware.Authorize(server.ActorRules, http.HandlerFunc(srv.ActorHandler))))
+	mux.Handle("/film/", middleware.Authenticate(middleware.Authorize(server.FilmRules, http.HandlerFunc(srv.FilmHandler))))
+	mux.Handle("/franchise/", middleware.Authenticate(middleware.Authorize(server.FranchiseRules, http.HandlerFunc(srv.FranchiseHandler))))
+	mux.Handle("/award/", middleware.Authenticate(middleware.Authorize(server.AwardRules, http.HandlerFunc(srv.AwardHandler))))
+	mux.Handle("/ceremony/", middleware.Authenticate(middleware.Authorize(server.CeremonyRules, http.HandlerFunc(srv.CeremonyHandler))))
+	mux.Handle("/search/", middleware.Authenticate(middleware.Authorize(server.SearchRules, http.HandlerFunc(srv.SearchHandler))))
+	mux.Handle("/trash/", middleware.Authenticate(middleware.Authorize(server.TrashRules, http.HandlerFunc(srv.TrashHandler))))
+	mux.Handle("/proposal/", middleware.Authenticate(middleware.Authorize(server.ProposalRules, http.HandlerFunc(srv.ProposalHandler))))
+	mux.Handle("/moderation/", middleware.Authenticate(middleware.Authorize(server.ModerationRules, http.HandlerFunc(srv.ModerationHandler))))
+	mux.Handle("/users/", middleware.Authenticate(middleware.Authorize(server.UserRules, http.HandlerFunc(srv.UserHandler))))
+	mux.Handle("/role/", middleware.Authenticate(middleware.Authorize(server.RoleRules, http.HandlerFunc(srv.RoleHandler))))
+	mux.Handle("/api-key/", middleware.Authenticate(middleware.Authorize(server.APIKeyRules, http.HandlerFunc(srv.APIKeyHandler))))
+	mux.Handle("/debug/vars", middleware.Authenticate(middleware.Authorize(server.MetricsRules, http.HandlerFunc(srv.Metrics))))
+	mux.Handle("/media/", http.StripPrefix("/media/", middleware.NoDirListing(http.FileServer(http.Dir(mediaDir)))))
 	mux.HandleFunc("/swagger/", httpSwagger.Handler(httpSwagger.URL(fmt.Sprintf("http://localhost:%v/swagger/doc.json", port))))
 
 	handler := middleware.Logger(mux)
 
 	log.Printf("starting server on port %v", port)
-	err := http.ListenAndServe(":"+port, handler)
+	err = http.ListenAndServe(":"+port, handler)
 	if !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("server closed due error: %v", err)
 	}
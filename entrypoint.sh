@@ -1,3 +1,4 @@
 go build -o server ./cmd/main.go
+go build -o admin ./cmd/admin
 
 ./server
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+)
+
+// Rule declares the permission required by requests matching Method and
+// Path. An empty Method matches any method. Path segments are matched
+// literally except "*", which matches any single segment, and a trailing
+// "**", which matches the rest of the path.
+type Rule struct {
+	Method     string
+	Path       string
+	Permission string
+}
+
+func (rule *Rule) matches(r *http.Request) bool {
+	if rule.Method != "" && rule.Method != r.Method {
+		return false
+	}
+	pattern := strings.Split(strings.Trim(rule.Path, "/"), "/")
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i, segment := range pattern {
+		if segment == "**" {
+			return true
+		}
+		if i >= len(path) || (segment != "*" && segment != path[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// Authorize checks that the role of the current user grants the permission
+// of the first matching rule. Requests matching no rule are forbidden, so
+// every route has to be declared. It should be used after Authenticate.
+func Authorize(rules []Rule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rule *Rule
+		for i := range rules {
+			if rules[i].matches(r) {
+				rule = &rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("forbidden"))
+			return
+		}
+		allowed, err := auth.HasPermission(CurrentUser(r).Role, rule.Permission)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get permissions from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if !allowed {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("forbidden"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		method string
+		path   string
+		want   bool
+	}{
+		{"exact path", Rule{Path: "/film/"}, "GET", "/film/", true},
+		{"exact path without slashes", Rule{Path: "film"}, "GET", "/film", true},
+		{"any method", Rule{Path: "/film/"}, "DELETE", "/film/", true},
+		{"method matches", Rule{Method: "POST", Path: "/film/"}, "POST", "/film/", true},
+		{"method differs", Rule{Method: "POST", Path: "/film/"}, "GET", "/film/", false},
+		{"literal differs", Rule{Path: "/film/"}, "GET", "/actor/", false},
+		{"longer path", Rule{Path: "/film/"}, "GET", "/film/5", false},
+		{"shorter path", Rule{Path: "/film/*"}, "GET", "/film/", false},
+		{"wildcard segment", Rule{Path: "/film/*"}, "GET", "/film/5", true},
+		{"wildcard in the middle", Rule{Path: "/film/*/revisions"}, "GET", "/film/5/revisions", true},
+		{"wildcard does not span segments", Rule{Path: "/film/*"}, "GET", "/film/5/revisions", false},
+		{"trailing double wildcard", Rule{Path: "/trash/**"}, "DELETE", "/trash/film/5", true},
+		{"double wildcard matches the prefix itself", Rule{Path: "/trash/**"}, "GET", "/trash/", true},
+		{"double wildcard keeps the prefix", Rule{Path: "/trash/**"}, "GET", "/film/5", false},
+		{"double wildcard after a wildcard", Rule{Path: "/film/*/**"}, "POST", "/film/5/revisions/3/revert", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := tt.rule.matches(r); got != tt.want {
+				t.Errorf("%+v matches %v %v = %v, want %v", tt.rule, tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
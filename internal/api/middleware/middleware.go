@@ -1,15 +1,39 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
 	"github.com/ffdb42/vk_trainee_task/internal/constants"
-	"github.com/ffdb42/vk_trainee_task/internal/db"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
 )
 
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	sessionKey
+)
+
+// CurrentUser returns the user authenticated by Authenticate, or nil if the
+// request was not authenticated.
+func CurrentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userKey).(*models.User)
+	return user
+}
+
+// CurrentSession returns the session the request was authenticated with, or
+// nil if it was authenticated otherwise.
+func CurrentSession(r *http.Request) *models.Session {
+	session, _ := r.Context().Value(sessionKey).(*models.Session)
+	return session
+}
+
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -18,36 +42,133 @@ func Logger(next http.Handler) http.Handler {
 	})
 }
 
-func Authenticate(next http.Handler) http.Handler {
+func NoDirListing(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		name, pass, ok := r.BasicAuth()
-		if !ok {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("auth data was not provided"))
+		if strings.HasSuffix(r.URL.Path, "/") || r.URL.Path == "" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		user, err := db.Instance().GetUser(name)
+// Authenticate accepts an API key, a bearer access token issued by
+// /auth/token, Basic auth credentials or a session cookie issued by
+// /auth/login. Bearer tokens are verified without a database lookup, API keys
+// are limited to the routes their scopes grant, unsafe requests with a
+// session cookie require the CSRF token.
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *models.User
+		var session *models.Session
+		issuedAt := time.Now()
+		if key := r.Header.Get(constants.APIKeyHeader); key != "" {
+			var err error
+			user, err = auth.AuthenticateAPIKey(key, r)
+			switch {
+			case errors.Is(err, auth.ErrInvalidAPIKey) || errors.Is(err, auth.ErrAPIKeyExpired):
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(err.Error()))
+				return
+			case errors.Is(err, auth.ErrIPNotAllowed) || errors.Is(err, auth.ErrScopeForbidden):
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(err.Error()))
+				return
+			case err != nil:
+				log.Printf("ERROR %v %v: cannot get api key from db: %v", r.Method, r.RequestURI, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("internal server error"))
+				return
+			}
+		} else if token, ok := bearerToken(r); ok {
+			claims, err := auth.Instance().Verify(token, time.Now())
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			user = claims.User()
+			issuedAt = time.Unix(claims.IssuedAt, 0)
+		} else if cookie, err := r.Cookie(constants.SessionCookieName); err == nil && r.Header.Get("Authorization") == "" {
+			session, err = auth.AuthenticateSession(cookie.Value, r)
+			switch {
+			case errors.Is(err, auth.ErrInvalidSession):
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(err.Error()))
+				return
+			case errors.Is(err, auth.ErrInvalidCSRF):
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(err.Error()))
+				return
+			case err != nil:
+				log.Printf("ERROR %v %v: cannot get session from db: %v", r.Method, r.RequestURI, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("internal server error"))
+				return
+			}
+			user = &models.User{ID: session.UserID, Name: session.Login, Role: session.Role}
+			issuedAt = session.CreatedAt
+		} else {
+			name, pass, ok := r.BasicAuth()
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("auth data was not provided"))
+				return
+			}
+			var err error
+			user, err = auth.Login(name, pass, auth.ClientIP(r))
+			var lockedErr *auth.LockedError
+			if errors.As(err, &lockedErr) {
+				w.Header().Set("Retry-After", lockedErr.RetryAfterSeconds())
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(lockedErr.Error()))
+				return
+			}
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidCredentials) {
+					log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("unauthorized"))
+				return
+			}
+		}
 
-		if err != nil || user == nil {
-			log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("unauthorized"))
-			return
+		err := auth.CheckAccount(user, issuedAt)
+		if errors.Is(err, auth.ErrPasswordResetRequired) && r.URL.Path == constants.ChangePasswordPath {
+			// The password can still be changed to lift the restriction.
+			err = nil
 		}
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pass))
-		if err != nil || user == nil {
+		if errors.Is(err, auth.ErrCredentialsChanged) {
 			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("unauthorized"))
+			w.Write([]byte("token revoked, sign in again"))
 			return
 		}
-
-		if r.Method != http.MethodGet && user.Role != constants.AdminRole {
+		if errors.Is(err, auth.ErrAccountDisabled) || errors.Is(err, auth.ErrPasswordResetRequired) {
 			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte("forbidden"))
+			w.Write([]byte(err.Error()))
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get users from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userKey, user)
+		if session != nil {
+			ctx = context.WithValue(ctx, sessionKey, session)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
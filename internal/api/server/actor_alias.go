@@ -0,0 +1,269 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+func (s *Server) actorAliasesHandler(actorID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if actorID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	aliasID, err := utils.ParseSegmentID(segments, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid alias id"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.getActorAliases(actorID, w, r)
+	case http.MethodPost:
+		s.postActorAlias(actorID, w, r)
+	case http.MethodPut:
+		s.putActorAlias(actorID, aliasID, w, r)
+	case http.MethodDelete:
+		s.deleteActorAlias(actorID, aliasID, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+// @Summary Get actor aliases
+// @Tags actor
+// @Description Псевдонимы и альтернативные имена актера
+// @ID get-actor-aliases
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetActorAliases
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/aliases [get]
+func (*Server) getActorAliases(actorID int, w http.ResponseWriter, r *http.Request) {
+	aliases, err := db.Instance().GetActorAliases(actorID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get aliases from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"aliases": aliases})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Add actor alias
+// @Tags actor
+// @Description Добавление псевдонима актера. Псевдоним с primary=true становится отображаемым именем актера (display_name)
+// @ID post-actor-alias
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param requestBody body models.ActorAliasPost true "Псевдоним: type - stage, birth, maiden, married, transliteration или other"
+// @Success 200 {string} string "alias added"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/aliases [post]
+func (*Server) postActorAlias(actorID int, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var aliasPost models.ActorAliasPost
+	err = json.Unmarshal(body, &aliasPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if aliasPost.Name == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("name was not provided"))
+		return
+	}
+	if msg := validateActorAlias(&aliasPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	alias := models.ActorAlias{ActorID: actorID, Type: constants.AliasOther}
+	_, err = db.Instance().AddActorAlias(alias.CopyWith(&aliasPost))
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("alias already exists"))
+		return
+	}
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("alias added"))
+}
+
+// @Summary Update actor alias
+// @Tags actor
+// @Description Изменение псевдонима актера, в том числе выбор его отображаемым именем
+// @ID put-actor-alias
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id актера"
+// @Param alias_id path int true "id псевдонима"
+// @Param requestBody body models.ActorAliasPost true "Псевдоним"
+// @Success 200 {string} string "alias updated"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/aliases/{alias_id} [put]
+func (*Server) putActorAlias(actorID int, aliasID int, w http.ResponseWriter, r *http.Request) {
+	if aliasID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid alias id"))
+		return
+	}
+	oldAlias, err := db.Instance().GetActorAlias(actorID, aliasID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if oldAlias == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var aliasPost models.ActorAliasPost
+	err = json.Unmarshal(body, &aliasPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if msg := validateActorAlias(&aliasPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	err = db.Instance().UpdateActorAlias(oldAlias.CopyWith(&aliasPost))
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("alias already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update alias: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("alias updated"))
+}
+
+// @Summary Delete actor alias
+// @Tags actor
+// @Description Удаление псевдонима актера
+// @ID delete-actor-alias
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id актера"
+// @Param alias_id path int true "id псевдонима"
+// @Success 200 {string} string "alias deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/aliases/{alias_id} [delete]
+func (*Server) deleteActorAlias(actorID int, aliasID int, w http.ResponseWriter, r *http.Request) {
+	if aliasID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid alias id"))
+		return
+	}
+	n, err := db.Instance().DeleteActorAlias(actorID, aliasID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("alias deleted"))
+}
+
+func validateActorAlias(alias *models.ActorAliasPost) string {
+	if alias.Name != nil && (len(*alias.Name) == 0 || len(*alias.Name) > 200) {
+		return "alias length should be at least 1 and no more than 200 characters"
+	}
+	if alias.Type != nil {
+		switch *alias.Type {
+		case constants.AliasStage, constants.AliasBirth, constants.AliasMaiden, constants.AliasMarried, constants.AliasTransliteration, constants.AliasOther:
+		default:
+			return "type should be 'stage', 'birth', 'maiden', 'married', 'transliteration' or 'other'"
+		}
+	}
+	if alias.Lang != nil {
+		lang, ok := utils.NormalizeLanguage(*alias.Lang)
+		if !ok {
+			return "invalid language"
+		}
+		alias.Lang = &lang
+	}
+	return ""
+}
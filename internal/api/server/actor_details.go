@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// validateActorDetails checks the biography fields of the actor and
+// normalizes nationalities to upper case ISO 3166-1 alpha-2 codes.
+func validateActorDetails(details *models.ActorDetails) string {
+	if details == nil {
+		return ""
+	}
+	if details.Birthplace != nil && (len(*details.Birthplace) == 0 || len(*details.Birthplace) > 200) {
+		return "birthplace length should be at least 1 and no more than 200 characters"
+	}
+	if details.Biography != nil && len(*details.Biography) > 5000 {
+		return "biography len should not exceed 5000 symbols"
+	}
+	seen := map[string]bool{}
+	for i, nationality := range details.Nationalities {
+		nationality = strings.ToUpper(nationality)
+		if !countryCode.MatchString(nationality) {
+			return "nationality should be an ISO 3166-1 alpha-2 code"
+		}
+		if seen[nationality] {
+			return fmt.Sprintf("duplicate nationality %v", nationality)
+		}
+		seen[nationality] = true
+		details.Nationalities[i] = nationality
+	}
+	return ""
+}
+
+// validateDeathDate checks that the death date follows the birthdate and that
+// every film released after it credits the actor posthumously.
+func validateDeathDate(birthdate, deathDate *models.CustomDate, films []*models.ActorFilm) string {
+	if deathDate == nil {
+		return ""
+	}
+	if birthdate != nil && !deathDate.After(birthdate.Time) {
+		return "death date should follow birthdate"
+	}
+	for _, film := range films {
+		if !film.Posthumous && film.ReleaseDate != nil && film.ReleaseDate.After(deathDate.Time) {
+			return fmt.Sprintf("death date precedes release of film %v which is not flagged posthumous", film.ID)
+		}
+	}
+	return ""
+}
+
+// validateFilmCredits checks that no credited actor died before the film's
+// release unless the credit is flagged posthumous.
+func validateFilmCredits(releaseDate *models.CustomDate, actorIDs []int, posthumous []int) (string, error) {
+	if releaseDate == nil || releaseDate.IsZero() || len(actorIDs) == 0 {
+		return "", nil
+	}
+	deathDates, err := db.Instance().GetActorsDeathDates(actorIDs)
+	if err != nil {
+		return "", err
+	}
+	flagged := map[int]bool{}
+	for _, actorID := range posthumous {
+		flagged[actorID] = true
+	}
+	for _, actorID := range actorIDs {
+		deathDate, ok := deathDates[actorID]
+		if ok && !flagged[actorID] && releaseDate.After(deathDate) {
+			return fmt.Sprintf("actor %v died before the film's release, the credit should be flagged posthumous", actorID), nil
+		}
+	}
+	return "", nil
+}
+
+// filmCredits returns actors credited in the film after the update is applied
+// along with the posthumous ones.
+func filmCredits(filmID int, filmPut *models.FilmPut) ([]int, []int, error) {
+	actors, err := db.Instance().GetFilmActors(filmID)
+	if err != nil {
+		return nil, nil, err
+	}
+	removed := map[int]bool{}
+	for _, actorID := range filmPut.RemoveActors {
+		removed[actorID] = true
+	}
+	credited := []int{}
+	for _, actor := range actors {
+		if !removed[actor.ID] {
+			credited = append(credited, actor.ID)
+		}
+	}
+	for _, actorID := range filmPut.ActorsList {
+		if !removed[actorID] {
+			credited = append(credited, actorID)
+		}
+	}
+	posthumous := filmPut.PosthumousActors
+	if posthumous == nil {
+		posthumous, err = db.Instance().GetFilmPosthumousActors(filmID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return credited, posthumous, nil
+}
+
+// saveActorDetails merges provided biography into the stored one.
+func saveActorDetails(tx *db.DBProvider, actorID int, details *models.ActorDetails) error {
+	if details == nil {
+		return nil
+	}
+	stored, err := tx.GetActorDetails(actorID)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		stored = &models.ActorDetails{}
+	}
+	return tx.SetActorDetails(actorID, stored.CopyWith(details))
+}
+
+// setActorAges fills the current age of the actor, or the age at death, and
+// the age at the release of every film of the filmography.
+func setActorAges(actor *models.Actor, films []*models.ActorFilm) {
+	if actor.Birthdate == nil || actor.Birthdate.IsZero() {
+		return
+	}
+	at := time.Now()
+	var deathDate *models.CustomDate
+	if actor.Details != nil && actor.Details.DeathDate != nil {
+		deathDate = actor.Details.DeathDate
+		at = deathDate.Time
+	}
+	age := utils.Age(actor.Birthdate.Time, at)
+	actor.Age = &age
+	for _, film := range films {
+		if film.ReleaseDate == nil || film.ReleaseDate.IsZero() {
+			continue
+		}
+		if deathDate != nil && film.ReleaseDate.After(deathDate.Time) {
+			continue
+		}
+		ageAtRelease := utils.Age(actor.Birthdate.Time, film.ReleaseDate.Time)
+		film.AgeAtRelease = &ageAtRelease
+	}
+}
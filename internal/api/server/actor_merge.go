@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// @Summary Merge actors
+// @Tags actor
+// @Description Объединение двух записей об одном и том же актере. Роли, номинации, псевдонимы, переводы, биография и фото дубликата переходят актеру {id}, имя дубликата сохраняется как псевдоним, сам дубликат удаляется, а запрос GET /actor/{duplicate_id} перенаправляет на актера {id}. При совпадении записей сохраняются записи актера {id}
+// @ID merge-actors
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id актера, который остается"
+// @Param requestBody body models.ActorMergePost true "id дубликата"
+// @Success 200 {string} string "actors merged"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/merge [post]
+func (s *Server) mergeActors(id int, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+		return
+	}
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var mergePost models.ActorMergePost
+	err = json.Unmarshal(body, &mergePost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if mergePost.DuplicateID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid duplicate id"))
+		return
+	}
+	if mergePost.DuplicateID == id {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("actor cannot be merged with itself"))
+		return
+	}
+	var dropped *models.Image
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		duplicate, err := loadActorSnapshot(tx, mergePost.DuplicateID)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		duplicateFilms, err := tx.GetActorFilms(mergePost.DuplicateID)
+		if err != nil {
+			return fmt.Errorf("cannot get films list from db: %w", err)
+		}
+		survivorFilms, err := tx.GetActorFilms(id)
+		if err != nil {
+			return fmt.Errorf("cannot get films list from db: %w", err)
+		}
+		dropped, err = tx.MergeActors(id, mergePost.DuplicateID)
+		if err != nil {
+			return err
+		}
+		credited := map[int]bool{}
+		for _, film := range survivorFilms {
+			credited[film.ID] = true
+		}
+		for _, film := range duplicateFilms {
+			err = recordRevision(tx, r, constants.RevisionEntityCast, film.ID, constants.RevisionDelete, castSnapshot(film.ID, duplicate.ID), nil)
+			if err != nil {
+				return err
+			}
+			if credited[film.ID] {
+				continue
+			}
+			err = recordRevision(tx, r, constants.RevisionEntityCast, film.ID, constants.RevisionCreate, nil, castSnapshot(film.ID, id))
+			if err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, r, constants.RevisionEntityActor, duplicate.ID, constants.RevisionDelete, duplicate, nil)
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot merge actors: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if dropped != nil {
+		s.removeImageBlobs(dropped)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("actors merged"))
+}
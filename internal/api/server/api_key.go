@@ -0,0 +1,345 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// APIKeyRules declares permissions required by /api-key/ routes.
+var APIKeyRules = []middleware.Rule{
+	{Path: "/api-key/**", Permission: constants.PermissionAPIKeysManage},
+}
+
+func (s *Server) APIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.getAPIKeys(w, r)
+		case http.MethodPost:
+			s.postAPIKey(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Unexpected HTTP method"))
+		}
+		return
+	}
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch {
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		s.getAPIKey(id, w, r)
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		s.revokeAPIKey(id, w, r)
+	case len(segments) == 3 && segments[2] == "rotate" && r.Method == http.MethodPost:
+		s.rotateAPIKey(id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get API keys
+// @Tags api-key
+// @Description Список API ключей. Сами ключи не хранятся, для опознания показывается их префикс. Требуется право api_keys:manage
+// @ID get-api-keys
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param login query string false "логин владельца ключей"
+// @Success 200 {object} models.GetAPIKeys
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api-key/ [get]
+func (*Server) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := 0
+	if login := r.URL.Query().Get("login"); login != "" {
+		user, err := db.Instance().GetUser(login)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if user == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("user not found"))
+			return
+		}
+		userID = user.ID
+	}
+	keys, err := db.Instance().GetAPIKeys(userID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get api keys from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeAPIKeyJSON(models.GetAPIKeys{APIKeys: keys}, http.StatusOK, w, r)
+}
+
+// @Summary Get API key
+// @Tags api-key
+// @Description Информация об API ключе по id. Требуется право api_keys:manage
+// @ID get-api-key
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api-key/{id} [get]
+func (*Server) getAPIKey(id int, w http.ResponseWriter, r *http.Request) {
+	key, err := db.Instance().GetAPIKey(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get api key from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if key == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	writeAPIKeyJSON(key, http.StatusOK, w, r)
+}
+
+// @Summary Add API key
+// @Tags api-key
+// @Description Создание API ключа для сервисных клиентов. Ключ передается в заголовке X-API-Key и показывается только в этом ответе. Права ключа ограничены его scopes и ролью владельца. Требуется право api_keys:manage
+// @ID add-api-key
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param requestBody body models.APIKeyPost true "Владелец, название, scopes, срок действия и разрешенные адреса"
+// @Success 201 {object} models.APIKeyCreated
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api-key/ [post]
+func (*Server) postAPIKey(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	keyPost := models.APIKeyPost{}
+	err = json.Unmarshal(body, &keyPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if msg := validateAPIKeyPost(&keyPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	currentUser := middleware.CurrentUser(r)
+	owner := currentUser
+	if keyPost.Login != nil && *keyPost.Login != currentUser.Name {
+		owner, err = db.Instance().GetUser(*keyPost.Login)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if owner == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("user not found"))
+			return
+		}
+	}
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate api key: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	apiKey := models.APIKey{
+		UserID:     owner.ID,
+		Name:       keyPost.Name,
+		Prefix:     prefix,
+		Scopes:     keyPost.Scopes,
+		AllowedIPs: keyPost.AllowedIPs,
+		CreatedBy:  currentUser.Name,
+		ExpiresAt:  keyPost.ExpiresAt,
+	}
+	id, err := db.Instance().AddAPIKey(&apiKey, hash)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add api key to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	created, err := db.Instance().GetAPIKey(id)
+	if err != nil || created == nil {
+		log.Printf("ERROR %v %v: cannot get api key from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeAPIKeyJSON(models.APIKeyCreated{APIKey: created, Key: key}, http.StatusCreated, w, r)
+}
+
+// @Summary Rotate API key
+// @Tags api-key
+// @Description Выпуск нового значения API ключа с теми же настройками. Старое значение перестает действовать сразу. Требуется право api_keys:manage
+// @ID rotate-api-key
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.APIKeyCreated
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api-key/{id}/rotate [post]
+func (*Server) rotateAPIKey(id int, w http.ResponseWriter, r *http.Request) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate api key: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	err = db.Instance().RotateAPIKey(id, prefix, hash)
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot rotate api key: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	rotated, err := db.Instance().GetAPIKey(id)
+	if err != nil || rotated == nil {
+		log.Printf("ERROR %v %v: cannot get api key from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeAPIKeyJSON(models.APIKeyCreated{APIKey: rotated, Key: key}, http.StatusOK, w, r)
+}
+
+// @Summary Revoke API key
+// @Tags api-key
+// @Description Отзыв API ключа. Требуется право api_keys:manage
+// @ID revoke-api-key
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param id path int true "id"
+// @Success 200 {string} string "api key revoked"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /api-key/{id} [delete]
+func (*Server) revokeAPIKey(id int, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().RevokeAPIKey(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot revoke api key: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("api key revoked"))
+}
+
+// validateAPIKeyPost checks the request and normalizes scopes and addresses.
+func validateAPIKeyPost(keyPost *models.APIKeyPost) string {
+	keyPost.Name = strings.TrimSpace(keyPost.Name)
+	if len(keyPost.Name) == 0 || len(keyPost.Name) > 100 {
+		return "name length should be at least 1 and no more than 100 characters"
+	}
+	if len(keyPost.Scopes) == 0 {
+		return "at least one scope should be provided"
+	}
+	known := map[string]bool{}
+	for _, scope := range auth.Scopes {
+		known[scope] = true
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, scope := range keyPost.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !known[scope] {
+			return fmt.Sprintf("unknown scope %v, available scopes: %v", scope, strings.Join(auth.Scopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	keyPost.Scopes = scopes
+	allowedIPs := []string{}
+	for _, value := range keyPost.AllowedIPs {
+		allowedIP, err := auth.ParseAllowedIP(value)
+		if err != nil {
+			return fmt.Sprintf("invalid allowed ip %v, it should be an address or a CIDR range", value)
+		}
+		allowedIPs = append(allowedIPs, allowedIP)
+	}
+	keyPost.AllowedIPs = allowedIPs
+	if keyPost.ExpiresAt != nil && !keyPost.ExpiresAt.After(time.Now()) {
+		return "expires_at should be in the future"
+	}
+	return ""
+}
+
+func writeAPIKeyJSON(respond any, status int, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(res)
+}
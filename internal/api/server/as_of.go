@@ -0,0 +1,417 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// Layouts accepted by as_of. Dates without time mean the end of the day, so
+// that changes made on that day are included.
+var (
+	asOfDateLayouts = []string{"02.01.2006", "2006-01-02"}
+	asOfTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "02.01.2006 15:04:05"}
+)
+
+// parseAsOf returns the as_of query parameter, or nil if it was not provided.
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("as_of"))
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range asOfDateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			asOf := date.Add(24*time.Hour - time.Nanosecond)
+			return &asOf, nil
+		}
+	}
+	for _, layout := range asOfTimeLayouts {
+		asOf, err := time.Parse(layout, value)
+		if err == nil {
+			asOf = asOf.UTC()
+			return &asOf, nil
+		}
+	}
+	return nil, errors.New("as_of should be a date in dd.mm.yyyy or an ISO 8601 timestamp")
+}
+
+// writeAsOfError writes the as_of parsing error. It returns true if there
+// was one.
+func writeAsOfError(err error, w http.ResponseWriter) bool {
+	if err == nil {
+		return false
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(err.Error()))
+	return true
+}
+
+func (*Server) getFilmAsOf(id int, asOf time.Time, w http.ResponseWriter, r *http.Request) {
+	film, err := filmAsOf(id, asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get film history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	if film == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	cast, err := db.Instance().GetFilmCastAsOf(id, asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get cast history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	actors, err := actorsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actors history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond := models.FilmRespond{Film: film, Actors: []*models.Actor{}}
+	for _, link := range cast {
+		if actor, ok := actors[link.ActorID]; ok {
+			respond.Actors = append(respond.Actors, actor)
+		}
+	}
+	err = newLocalizer(w, r).filmResponds(&respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	writeAsOf(respond, w, r)
+}
+
+func (*Server) getActorAsOf(id int, asOf time.Time, w http.ResponseWriter, r *http.Request) {
+	actor, err := actorAsOf(id, asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actor history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	if actor == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	cast, err := db.Instance().GetActorCastAsOf(id, asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get cast history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	films, err := filmsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get films history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	respond := models.ActorRespond{Actor: actor, Films: []*models.ActorFilm{}}
+	for _, link := range cast {
+		if film, ok := films[link.FilmID]; ok {
+			respond.Films = append(respond.Films, &models.ActorFilm{Film: film})
+		}
+	}
+	setActorAges(actor, respond.Films)
+	err = newLocalizer(w, r).actorResponds(&respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	writeAsOf(respond, w, r)
+}
+
+func (*Server) getFilmsAsOf(asOf time.Time, sortBy models.SortBy, sortOrder models.SortOrder, w http.ResponseWriter, r *http.Request) {
+	films, err := filmsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get films history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get films"))
+		return
+	}
+	actors, err := actorsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actors history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get films"))
+		return
+	}
+	cast, err := db.Instance().GetCastAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get cast history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get films"))
+		return
+	}
+	filmsActors := map[int][]*models.Actor{}
+	for _, link := range cast {
+		if actor, ok := actors[link.ActorID]; ok {
+			filmsActors[link.FilmID] = append(filmsActors[link.FilmID], actor)
+		}
+	}
+	respond := []*models.FilmRespond{}
+	for _, film := range sortFilms(films, sortBy, sortOrder) {
+		filmRespond := models.FilmRespond{Film: film, Actors: []*models.Actor{}}
+		filmRespond.Actors = append(filmRespond.Actors, filmsActors[film.ID]...)
+		respond = append(respond, &filmRespond)
+	}
+	err = newLocalizer(w, r).filmResponds(respond...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get films"))
+		return
+	}
+	writeAsOf(map[string]any{"films": respond}, w, r)
+}
+
+func (*Server) getActorsAsOf(asOf time.Time, w http.ResponseWriter, r *http.Request) {
+	actors, err := actorsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actors history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actors"))
+		return
+	}
+	films, err := filmsAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get films history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actors"))
+		return
+	}
+	cast, err := db.Instance().GetCastAsOf(asOf)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get cast history: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actors"))
+		return
+	}
+	actorsFilms := map[int][]*models.ActorFilm{}
+	for _, link := range cast {
+		if film, ok := films[link.FilmID]; ok {
+			actorsFilms[link.ActorID] = append(actorsFilms[link.ActorID], &models.ActorFilm{Film: film})
+		}
+	}
+	ids := []int{}
+	for id := range actors {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	respond := []*models.ActorRespond{}
+	for _, id := range ids {
+		actorRespond := models.ActorRespond{Actor: actors[id], Films: []*models.ActorFilm{}}
+		actorRespond.Films = append(actorRespond.Films, actorsFilms[id]...)
+		setActorAges(actorRespond.Actor, actorRespond.Films)
+		respond = append(respond, &actorRespond)
+	}
+	err = newLocalizer(w, r).actorResponds(respond...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actors"))
+		return
+	}
+	writeAsOf(map[string]any{"actors": respond}, w, r)
+}
+
+func writeAsOf(respond any, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// filmAsOf reconstructs the film as it was at asOf: the state before the first
+// change made after asOf or, if there was none, the current film. Nil means
+// that the film did not exist at asOf.
+func filmAsOf(id int, asOf time.Time) (*models.Film, error) {
+	revision, err := db.Instance().GetStateAsOf(constants.RevisionEntityFilm, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if revision != nil {
+		return decodeFilm(revision.Before)
+	}
+	film, err := db.Instance().GetFilm(id)
+	if err != nil || film.ID == 0 {
+		return nil, err
+	}
+	return film, nil
+}
+
+// actorAsOf reconstructs the actor the same way as filmAsOf. Ages are left
+// to the caller, since they depend on the filmography.
+func actorAsOf(id int, asOf time.Time) (*models.Actor, error) {
+	revision, err := db.Instance().GetStateAsOf(constants.RevisionEntityActor, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if revision != nil {
+		return decodeActor(revision.Before)
+	}
+	actor, err := db.Instance().GetActor(id)
+	if err != nil || actor.ID == 0 {
+		return nil, err
+	}
+	actor.Details, err = db.Instance().GetActorDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+// filmsAsOf reconstructs all films that existed at asOf by their ids.
+func filmsAsOf(asOf time.Time) (map[int]*models.Film, error) {
+	films, err := db.Instance().GetFilms(constants.SortByName, constants.SortAsc, &models.FilmsFilter{})
+	if err != nil {
+		return nil, err
+	}
+	res := map[int]*models.Film{}
+	for i := range *films {
+		res[(*films)[i].ID] = &(*films)[i]
+	}
+	revisions, err := db.Instance().GetStatesAsOf(constants.RevisionEntityFilm, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		film, err := decodeFilm(revision.Before)
+		if err != nil {
+			return nil, err
+		}
+		if film == nil {
+			delete(res, revision.EntityID)
+			continue
+		}
+		res[revision.EntityID] = film
+	}
+	return res, nil
+}
+
+func actorsAsOf(asOf time.Time) (map[int]*models.Actor, error) {
+	actors, err := db.Instance().GetActors()
+	if err != nil {
+		return nil, err
+	}
+	res := map[int]*models.Actor{}
+	ids := []int{}
+	for i := range *actors {
+		res[(*actors)[i].ID] = &(*actors)[i]
+		ids = append(ids, (*actors)[i].ID)
+	}
+	details, err := db.Instance().GetActorsDetails(ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, actorDetails := range details {
+		res[id].Details = actorDetails
+	}
+	revisions, err := db.Instance().GetStatesAsOf(constants.RevisionEntityActor, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		actor, err := decodeActor(revision.Before)
+		if err != nil {
+			return nil, err
+		}
+		if actor == nil {
+			delete(res, revision.EntityID)
+			continue
+		}
+		res[revision.EntityID] = actor
+	}
+	return res, nil
+}
+
+// decodeFilm reads the film from a revision snapshot, nil stands for a film
+// that did not exist.
+func decodeFilm(state json.RawMessage) (*models.Film, error) {
+	if state == nil {
+		return nil, nil
+	}
+	film := models.Film{}
+	return &film, json.Unmarshal(state, &film)
+}
+
+// decodeActor reads the actor with its details, which hold the death date
+// for the ages.
+func decodeActor(state json.RawMessage) (*models.Actor, error) {
+	if state == nil {
+		return nil, nil
+	}
+	actor := models.Actor{}
+	err := json.Unmarshal(state, &actor)
+	if err != nil {
+		return nil, err
+	}
+	actor.ExternalIDs = nil
+	return &actor, nil
+}
+
+// sortFilms orders reconstructed films the way GetFilms does. Box office is
+// not part of the history, so such films are sorted by rating.
+func sortFilms(films map[int]*models.Film, sortBy models.SortBy, sortOrder models.SortOrder) []*models.Film {
+	res := []*models.Film{}
+	for _, film := range films {
+		res = append(res, film)
+	}
+	less := func(a, b *models.Film) bool {
+		switch sortBy {
+		case constants.SortByName:
+			return filmName(a) < filmName(b)
+		case constants.SortByReleaseDate:
+			return a.ReleaseDate != nil && b.ReleaseDate != nil && a.ReleaseDate.Before(b.ReleaseDate.Time)
+		default:
+			return filmRating(a) < filmRating(b)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if sortOrder == constants.SortDesc {
+			return less(res[j], res[i])
+		}
+		return less(res[i], res[j])
+	})
+	return res
+}
+
+func filmName(film *models.Film) string {
+	if film.Name == nil {
+		return ""
+	}
+	return *film.Name
+}
+
+func filmRating(film *models.Film) int {
+	if film.Rating == nil {
+		return 0
+	}
+	return *film.Rating
+}
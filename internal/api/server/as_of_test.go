@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	endOfDay := time.Date(2020, 3, 15, 23, 59, 59, int(time.Second-time.Nanosecond), time.UTC)
+	tests := []struct {
+		name    string
+		asOf    string
+		want    *time.Time
+		wantErr bool
+	}{
+		{"not provided", "", nil, false},
+		{"blank", "  ", nil, false},
+		{"date", "15.03.2020", &endOfDay, false},
+		{"iso date", "2020-03-15", &endOfDay, false},
+		{"padded date", " 15.03.2020 ", &endOfDay, false},
+		{"rfc 3339", "2020-03-15T10:30:00Z", ptr(time.Date(2020, 3, 15, 10, 30, 0, 0, time.UTC)), false},
+		{"rfc 3339 with offset", "2020-03-15T10:30:00+03:00", ptr(time.Date(2020, 3, 15, 7, 30, 0, 0, time.UTC)), false},
+		{"timestamp without zone", "2020-03-15T10:30:00", ptr(time.Date(2020, 3, 15, 10, 30, 0, 0, time.UTC)), false},
+		{"date with time", "15.03.2020 10:30:00", ptr(time.Date(2020, 3, 15, 10, 30, 0, 0, time.UTC)), false},
+		{"invalid day", "32.03.2020", nil, true},
+		{"american date", "03/15/2020", nil, true},
+		{"garbage", "yesterday", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/film/5?as_of="+url.QueryEscape(tt.asOf), nil)
+			got, err := parseAsOf(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("as_of = %v, want %v", got, tt.want)
+			case !got.Equal(*tt.want):
+				t.Errorf("as_of = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+func (s *Server) AuthHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) < 2 || len(segments) > 3 || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	switch {
+	case len(segments) == 2 && segments[1] == "token":
+		s.issueToken(w, r)
+	case len(segments) == 2 && segments[1] == "revoke":
+		s.revokeToken(w, r)
+	case len(segments) == 2 && segments[1] == "login":
+		s.login(w, r)
+	case len(segments) == 2 && segments[1] == "password-reset":
+		s.requestPasswordReset(w, r)
+	case len(segments) == 3 && segments[1] == "password-reset" && segments[2] == "confirm":
+		s.confirmPasswordReset(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Issue token
+// @Tags auth
+// @Description Выдача access и refresh токенов. grant_type password обменивает логин и пароль на токены, grant_type refresh_token обменивает refresh токен на новую пару, старый refresh токен при этом отзывается. Access токен передается в заголовке Authorization: Bearer
+// @ID issue-token
+// @Accept json
+// @Produce json
+// @Param requestBody body models.TokenRequest true "grant_type и логин с паролем или refresh токен"
+// @Success 200 {object} models.TokenRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 403 {string} string "account is disabled"
+// @Failure 429 {string} string "too many failed login attempts"
+// @Failure 500 {string} string "internal server error"
+// @Failure 503 {string} string "bearer tokens are not configured"
+// @Router /auth/token [post]
+func (s *Server) issueToken(w http.ResponseWriter, r *http.Request) {
+	if auth.Instance() == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(auth.ErrNotConfigured.Error()))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.TokenRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate refresh token: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	expiresAt := time.Now().Add(constants.RefreshTokenTTL)
+	var user *models.User
+	switch request.GrantType {
+	case constants.GrantTypePassword:
+		user, err = auth.Login(request.Name, request.Password, auth.ClientIP(r))
+		var lockedErr *auth.LockedError
+		if errors.As(err, &lockedErr) {
+			w.Header().Set("Retry-After", lockedErr.RetryAfterSeconds())
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(lockedErr.Error()))
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("unauthorized"))
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+	case constants.GrantTypeRefreshToken:
+		userID, err := db.Instance().RotateRefreshToken(auth.HashToken(request.RefreshToken), refreshHash, expiresAt)
+		if errors.Is(err, db.ErrNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("invalid refresh token"))
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot rotate refresh token: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		user, err = db.Instance().GetUserByID(userID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if user == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("invalid refresh token"))
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("grant_type should be password or refresh_token"))
+		return
+	}
+	err = auth.CheckAccount(user, time.Now())
+	if errors.Is(err, auth.ErrAccountDisabled) || errors.Is(err, auth.ErrPasswordResetRequired) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get users from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if request.GrantType == constants.GrantTypePassword {
+		err = db.Instance().AddRefreshToken(user.ID, refreshHash, expiresAt)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot add refresh token to db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+	}
+	accessToken, ttl, err := auth.Instance().Sign(user, time.Now())
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot sign access token: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(models.TokenRespond{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ttl.Seconds()),
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Revoke token
+// @Tags auth
+// @Description Отзыв refresh токена. Выданные по нему access токены действуют до истечения срока
+// @ID revoke-token
+// @Accept json
+// @Param requestBody body models.RevokeRequest true "refresh токен"
+// @Success 200 {string} string "token revoked"
+// @Failure 400 {string} string "error string"
+// @Failure 500 {string} string "internal server error"
+// @Router /auth/revoke [post]
+func (s *Server) revokeToken(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.RevokeRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil || request.RefreshToken == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("refresh_token was not provided"))
+		return
+	}
+	// Unknown tokens are not reported, the result for the caller is the same.
+	_, err = db.Instance().RevokeRefreshToken(auth.HashToken(request.RefreshToken))
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot revoke refresh token: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("token revoked"))
+}
+
+// RunAuthPurge removes expired refresh and password reset tokens, timed out
+// sessions and failed logins that are neither recent nor locked. It blocks, purging every interval.
+func (s *Server) RunAuthPurge(interval time.Duration) {
+	for {
+		now := time.Now()
+		n, err := db.Instance().PurgeRefreshTokens(now)
+		if err != nil {
+			log.Printf("ERROR cannot purge refresh tokens: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %v expired refresh tokens", n)
+		}
+		_, err = db.Instance().PurgeSessions(now.Add(-constants.SessionIdleTimeout), now)
+		if err != nil {
+			log.Printf("ERROR cannot purge sessions: %v", err)
+		}
+		_, err = db.Instance().PurgePasswordResetTokens(now)
+		if err != nil {
+			log.Printf("ERROR cannot purge password reset tokens: %v", err)
+		}
+		_, err = db.Instance().PurgeLoginAttempts(now.Add(-constants.LoginFailureWindow), now)
+		if err != nil {
+			log.Printf("ERROR cannot purge failed logins: %v", err)
+		}
+		_, err = db.Instance().PurgeDeletedUsers(now.Add(-constants.AccessTokenTTL))
+		if err != nil {
+			log.Printf("ERROR cannot purge deleted users: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
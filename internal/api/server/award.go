@@ -0,0 +1,804 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// AwardRules declares permissions required by /award/ routes.
+var AwardRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/award/**", Permission: constants.PermissionAwardsRead},
+	{Path: "/award/**", Permission: constants.PermissionAwardsWrite},
+}
+
+func (s *Server) AwardHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if len(segments) > 2 {
+		if segments[2] != "nominations" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		s.nominationsHandler(id, segments[3:], w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if id > 0 {
+			s.getAward(id, w, r)
+		} else {
+			s.getAwards(w, r)
+		}
+	case http.MethodPost:
+		s.postAward(w, r)
+	case http.MethodPut:
+		s.putAward(id, w, r)
+	case http.MethodDelete:
+		s.deleteAward(id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+func (s *Server) nominationsHandler(awardID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	nominationID, err := utils.ParseSegmentID(segments, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid nomination id"))
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.postNomination(awardID, w, r)
+	case http.MethodPut:
+		s.putNomination(awardID, nominationID, w, r)
+	case http.MethodDelete:
+		s.deleteNomination(awardID, nominationID, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+// CeremonyRules declares permissions required by /ceremony/ routes.
+var CeremonyRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/ceremony/**", Permission: constants.PermissionAwardsRead},
+}
+
+// @Summary Ceremonies
+// @Tags award
+// @Description Список церемоний с годами, за которые есть награды. /ceremony/{ceremony}/{year} возвращает все награды церемонии за год вместе с номинантами
+// @ID get-ceremonies
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} models.GetCeremonies
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /ceremony/ [get]
+func (s *Server) CeremonyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	segments := utils.PathSegments(r.URL.Path)
+	switch len(segments) {
+	case 1, 2:
+		s.getCeremonies(segments[1:], w, r)
+	case 3:
+		year, err := strconv.Atoi(segments[2])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid year"))
+			return
+		}
+		s.getCeremonyYear(segments[1], year, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+func (*Server) getCeremonies(segments []string, w http.ResponseWriter, r *http.Request) {
+	ceremonies, err := db.Instance().GetCeremonies()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get ceremonies from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	var respond any = map[string]any{"ceremonies": ceremonies}
+	if len(segments) > 0 {
+		respond = nil
+		for _, v := range ceremonies {
+			if v.Ceremony == segments[0] {
+				respond = v
+			}
+		}
+		if respond == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+	}
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Ceremony year
+// @Tags award
+// @Description Все награды церемонии за указанный год вместе с номинантами и победителями
+// @ID get-ceremony-year
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param ceremony path string true "название церемонии"
+// @Param year path int true "год"
+// @Success 200 {object} models.CeremonyYear
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /ceremony/{ceremony}/{year} [get]
+func (*Server) getCeremonyYear(ceremony string, year int, w http.ResponseWriter, r *http.Request) {
+	awards, err := db.Instance().GetAwards(ceremony, year)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get awards from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if len(awards) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	respond := models.CeremonyYear{Ceremony: ceremony, Year: year, Awards: []*models.AwardRespond{}}
+	for _, award := range awards {
+		nominations, err := db.Instance().GetAwardNominations(award.ID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get nominations from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		expanded, err := expandNominations(nominations, false, true, true)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot expand nominations: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		respond.Awards = append(respond.Awards, &models.AwardRespond{Award: award, Nominations: expanded})
+	}
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Get awards
+// @Tags award
+// @Description Получение списка наград
+// @ID get-awards
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param ceremony query string false "название церемонии"
+// @Param year query int false "год"
+// @Success 200 {object} models.GetAwards
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/ [get]
+func (*Server) getAwards(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	year := 0
+	if query.Has("year") {
+		var err error
+		year, err = strconv.Atoi(query.Get("year"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid year"))
+			return
+		}
+	}
+	awards, err := db.Instance().GetAwards(query.Get("ceremony"), year)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get awards"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"awards": awards})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Get award
+// @Tags award
+// @Description Получение награды по id вместе с номинантами
+// @ID get-award
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.AwardRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id} [get]
+func (*Server) getAward(id int, w http.ResponseWriter, r *http.Request) {
+	award, err := db.Instance().GetAward(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get award"))
+		return
+	}
+	if award == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	nominations, err := db.Instance().GetAwardNominations(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get nominations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get award"))
+		return
+	}
+	expanded, err := expandNominations(nominations, false, true, true)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot expand nominations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get award"))
+		return
+	}
+	res, err := json.Marshal(models.AwardRespond{Award: award, Nominations: expanded})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Add award
+// @Tags award
+// @Description Создание награды (церемония, год, категория)
+// @ID post-award
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param requestBody body models.AwardPost true "Информация о награде"
+// @Success 200 {string} string "award added"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/ [post]
+func (*Server) postAward(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var award models.Award
+	err = json.Unmarshal(body, &award)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if award.Ceremony == nil || award.Year == nil || award.Category == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("ceremony, year and category should be provided"))
+		return
+	}
+	if msg := validateAward(&award); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	_, err = db.Instance().AddAward(&award)
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("award already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("award added"))
+}
+
+// @Summary Update award
+// @Tags award
+// @Description Изменение награды
+// @ID put-award
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param requestBody body models.AwardPost true "Информация о награде"
+// @Success 200 {string} string "award updated"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id} [put]
+func (*Server) putAward(id int, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	oldAward, err := db.Instance().GetAward(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if oldAward == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var award models.Award
+	err = json.Unmarshal(body, &award)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if msg := validateAward(&award); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	err = db.Instance().UpdateAward(oldAward.CopyWith(&award))
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("award already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update award: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("award updated"))
+}
+
+// @Summary Delete award
+// @Tags award
+// @Description Удаление награды вместе со всеми номинациями
+// @ID delete-award
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Success 200 {string} string "award deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id} [delete]
+func (*Server) deleteAward(id int, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	n, err := db.Instance().DeleteAward(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("award deleted"))
+}
+
+// @Summary Add nomination
+// @Tags award
+// @Description Добавление номинации на награду. Если указан actor_id, номинируется работа актера в фильме, и актер должен быть указан в титрах фильма
+// @ID post-nomination
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id награды"
+// @Param requestBody body models.NominationPost true "Номинант"
+// @Success 200 {string} string "nomination added"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id}/nominations [post]
+func (*Server) postNomination(awardID int, w http.ResponseWriter, r *http.Request) {
+	if awardID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	award, err := db.Instance().GetAward(awardID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if award == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var nominationPost models.NominationPost
+	err = json.Unmarshal(body, &nominationPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if nominationPost.FilmID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid film id"))
+		return
+	}
+	if nominationPost.ActorID != nil && *nominationPost.ActorID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid actor id"))
+		return
+	}
+	nomination := models.Nomination{
+		AwardID: awardID,
+		FilmID:  nominationPost.FilmID,
+		ActorID: nominationPost.ActorID,
+		Won:     nominationPost.Won,
+	}
+	_, err = db.Instance().AddNomination(&nomination)
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("nomination already exists"))
+		return
+	}
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("film not found or actor is not credited in the film"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("nomination added"))
+}
+
+// @Summary Update nomination
+// @Tags award
+// @Description Отметка о победе в номинации
+// @ID put-nomination
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id награды"
+// @Param nomination_id path int true "id номинации"
+// @Param requestBody body models.NominationPut true "Победа"
+// @Success 200 {string} string "nomination updated"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id}/nominations/{nomination_id} [put]
+func (*Server) putNomination(awardID int, nominationID int, w http.ResponseWriter, r *http.Request) {
+	if awardID < 1 || nominationID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var nominationPut models.NominationPut
+	err = json.Unmarshal(body, &nominationPut)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if nominationPut.Won == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("won was not provided"))
+		return
+	}
+	n, err := db.Instance().UpdateNomination(awardID, nominationID, *nominationPut.Won)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update nomination: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("nomination updated"))
+}
+
+// @Summary Delete nomination
+// @Tags award
+// @Description Удаление номинации
+// @ID delete-nomination
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id награды"
+// @Param nomination_id path int true "id номинации"
+// @Success 200 {string} string "nomination deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /award/{id}/nominations/{nomination_id} [delete]
+func (*Server) deleteNomination(awardID int, nominationID int, w http.ResponseWriter, r *http.Request) {
+	if awardID < 1 || nominationID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	n, err := db.Instance().DeleteNomination(awardID, nominationID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("nomination deleted"))
+}
+
+// @Summary Get film awards
+// @Tags film
+// @Description Номинации и награды фильма, включая номинации актеров за роли в этом фильме
+// @ID get-film-awards
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetNominations
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/awards [get]
+func (*Server) getFilmAwards(filmID int, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+		return
+	}
+	if filmID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	nominations, err := db.Instance().GetFilmNominations(filmID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get nominations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeNominations(nominations, true, false, true, w, r)
+}
+
+// @Summary Get actor awards
+// @Tags actor
+// @Description Номинации и награды актера за роли в фильмах
+// @ID get-actor-awards
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetNominations
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/awards [get]
+func (*Server) getActorAwards(actorID int, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+		return
+	}
+	if actorID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	nominations, err := db.Instance().GetActorNominations(actorID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get nominations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeNominations(nominations, true, true, false, w, r)
+}
+
+func writeNominations(nominations []*models.Nomination, withAward, withFilm, withActor bool, w http.ResponseWriter, r *http.Request) {
+	expanded, err := expandNominations(nominations, withAward, withFilm, withActor)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot expand nominations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"nominations": expanded})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// expandNominations replaces ids in nominations with the referenced records,
+// fetching each award, film and actor only once.
+func expandNominations(nominations []*models.Nomination, withAward, withFilm, withActor bool) ([]*models.NominationRespond, error) {
+	awards := map[int]*models.Award{}
+	films := map[int]*models.Film{}
+	actors := map[int]*models.Actor{}
+	res := []*models.NominationRespond{}
+	for _, v := range nominations {
+		respond := models.NominationRespond{ID: v.ID, Won: v.Won}
+		if withAward {
+			if _, ok := awards[v.AwardID]; !ok {
+				award, err := db.Instance().GetAward(v.AwardID)
+				if err != nil {
+					return nil, err
+				}
+				awards[v.AwardID] = award
+			}
+			respond.Award = awards[v.AwardID]
+		}
+		if withFilm {
+			if _, ok := films[v.FilmID]; !ok {
+				film, err := db.Instance().GetFilm(v.FilmID)
+				if err != nil {
+					return nil, err
+				}
+				films[v.FilmID] = film
+			}
+			respond.Film = films[v.FilmID]
+		}
+		if withActor && v.ActorID != nil {
+			if _, ok := actors[*v.ActorID]; !ok {
+				actor, err := db.Instance().GetActor(*v.ActorID)
+				if err != nil {
+					return nil, err
+				}
+				actors[*v.ActorID] = actor
+			}
+			respond.Actor = actors[*v.ActorID]
+		}
+		res = append(res, &respond)
+	}
+	return res, nil
+}
+
+func validateAward(award *models.Award) string {
+	if award.Ceremony != nil && (len(*award.Ceremony) < 1 || len(*award.Ceremony) > 150) {
+		return "the length of the ceremony name must be at least 1 and no more than 150 characters"
+	}
+	if award.Category != nil && (len(*award.Category) < 1 || len(*award.Category) > 150) {
+		return "the length of the category must be at least 1 and no more than 150 characters"
+	}
+	if award.Year != nil && (*award.Year < 1900 || *award.Year > 2100) {
+		return "year should be from 1900 to 2100"
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// Weights of the name similarity and of the date match in the duplicate
+// score. A film released a year apart counts as half a date match.
+const (
+	nameWeight = 0.75
+	dateWeight = 0.25
+)
+
+// isForced reports whether the client asked to create the record despite
+// likely duplicates.
+func isForced(r *http.Request) bool {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	return force
+}
+
+// findActorDuplicates scores stored actors against the created one by its
+// name, translated names and aliases. An actor with another birthdate scores
+// below the threshold whatever the name, so only actors born on the same date
+// are loaded.
+func findActorDuplicates(actor *models.Actor) ([]*models.DuplicateMatch, error) {
+	if actor.Birthdate == nil || actor.Birthdate.IsZero() {
+		return []*models.DuplicateMatch{}, nil
+	}
+	candidates, err := db.Instance().GetActorsBornOn(actor.Birthdate.Time)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+	otherNames, err := db.Instance().GetActorsOtherNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	name := utils.NormalizeName(actorFullName(actor))
+	matches := []*models.DuplicateMatch{}
+	for _, candidate := range candidates {
+		names := append([]string{actorFullName(candidate)}, otherNames[candidate.ID]...)
+		score := nameWeight*bestSimilarity(name, names) + dateWeight
+		if score >= constants.DuplicateScoreThreshold {
+			matches = append(matches, &models.DuplicateMatch{Score: roundScore(score), Actor: candidate})
+		}
+	}
+	sortMatches(matches)
+	return matches, nil
+}
+
+// findFilmDuplicates scores stored films against the created one by its name
+// and translated names and by the release year. A film released two or more
+// years apart scores below the threshold whatever the name, so only films of
+// the neighbouring years are loaded.
+func findFilmDuplicates(film *models.Film) ([]*models.DuplicateMatch, error) {
+	if film.Name == nil || film.ReleaseDate == nil || film.ReleaseDate.IsZero() {
+		return []*models.DuplicateMatch{}, nil
+	}
+	year := film.ReleaseDate.Year()
+	candidates, err := db.Instance().GetFilmsReleasedIn(year-1, year+1)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+	otherNames, err := db.Instance().GetFilmsOtherNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	name := utils.NormalizeName(*film.Name)
+	matches := []*models.DuplicateMatch{}
+	for _, candidate := range candidates {
+		names := otherNames[candidate.ID]
+		if candidate.Name != nil {
+			names = append(names, *candidate.Name)
+		}
+		dateScore := 0.5
+		if candidate.ReleaseDate.Year() == year {
+			dateScore = 1
+		}
+		score := nameWeight*bestSimilarity(name, names) + dateWeight*dateScore
+		if score >= constants.DuplicateScoreThreshold {
+			matches = append(matches, &models.DuplicateMatch{Score: roundScore(score), Film: candidate})
+		}
+	}
+	sortMatches(matches)
+	return matches, nil
+}
+
+func actorFullName(actor *models.Actor) string {
+	name := ""
+	if actor.FirstName != nil {
+		name = *actor.FirstName
+	}
+	if actor.LastName != nil {
+		name += " " + *actor.LastName
+	}
+	return name
+}
+
+func bestSimilarity(name string, candidates []string) float64 {
+	best := 0.0
+	for _, candidate := range candidates {
+		best = max(best, utils.NameSimilarity(name, utils.NormalizeName(candidate)))
+	}
+	return best
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+func sortMatches(matches []*models.DuplicateMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+}
+
+func writeDuplicates(matches []*models.DuplicateMatch, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(map[string]any{"matches": matches})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	w.Write(res)
+}
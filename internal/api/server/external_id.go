@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var filmExternalIDFormats = map[models.ExternalSource]*regexp.Regexp{
+	constants.ExternalSourceIMDb:      regexp.MustCompile(`^tt[0-9]{7,10}$`),
+	constants.ExternalSourceKinopoisk: regexp.MustCompile(`^[1-9][0-9]{0,9}$`),
+	constants.ExternalSourceWikidata:  regexp.MustCompile(`^Q[1-9][0-9]*$`),
+}
+
+var actorExternalIDFormats = map[models.ExternalSource]*regexp.Regexp{
+	constants.ExternalSourceIMDb:      regexp.MustCompile(`^nm[0-9]{7,10}$`),
+	constants.ExternalSourceKinopoisk: regexp.MustCompile(`^[1-9][0-9]{0,9}$`),
+	constants.ExternalSourceWikidata:  regexp.MustCompile(`^Q[1-9][0-9]*$`),
+}
+
+// normalizeExternalID brings the source to lower case and the value to the
+// case used by the source.
+func normalizeExternalID(externalID *models.ExternalID) {
+	externalID.Source = models.ExternalSource(strings.ToLower(strings.TrimSpace(string(externalID.Source))))
+	externalID.Value = strings.TrimSpace(externalID.Value)
+	switch externalID.Source {
+	case constants.ExternalSourceIMDb:
+		externalID.Value = strings.ToLower(externalID.Value)
+	case constants.ExternalSourceWikidata:
+		externalID.Value = strings.ToUpper(externalID.Value)
+	}
+}
+
+func validateExternalIDs(ids []*models.ExternalID, formats map[models.ExternalSource]*regexp.Regexp) string {
+	seen := map[models.ExternalID]bool{}
+	for _, externalID := range ids {
+		if externalID == nil {
+			return "invalid external id"
+		}
+		normalizeExternalID(externalID)
+		format, ok := formats[externalID.Source]
+		if !ok {
+			return "external id source should be 'imdb', 'kinopoisk' or 'wikidata'"
+		}
+		if !format.MatchString(externalID.Value) {
+			return fmt.Sprintf("invalid %v id %v", externalID.Source, externalID.Value)
+		}
+		if seen[*externalID] {
+			return fmt.Sprintf("duplicate %v id %v", externalID.Source, externalID.Value)
+		}
+		seen[*externalID] = true
+	}
+	return ""
+}
+
+// checkExternalIDsOwner checks that none of the external ids belongs to
+// a record other than the one with ownerID.
+func checkExternalIDsOwner(ids []*models.ExternalID, ownerID int, lookup func(models.ExternalSource, string) (int, error)) (string, error) {
+	for _, externalID := range ids {
+		id, err := lookup(externalID.Source, externalID.Value)
+		if err != nil {
+			return "", err
+		}
+		if id > 0 && id != ownerID {
+			return fmt.Sprintf("%v id %v already belongs to record %v", externalID.Source, externalID.Value, id), nil
+		}
+	}
+	return "", nil
+}
+
+// @Summary Get film by external id
+// @Tags film
+// @Description Поиск фильма по идентификатору во внешнем каталоге: imdb (tt0111161), kinopoisk (326) или wikidata (Q172241)
+// @ID get-film-by-external-id
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param source path string true "imdb, kinopoisk или wikidata"
+// @Param value path string true "идентификатор"
+// @Success 200 {object} models.FilmRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/by-external/{source}/{value} [get]
+func (s *Server) getFilmByExternalID(segments []string, w http.ResponseWriter, r *http.Request) {
+	id, ok := resolveExternalID(segments, filmExternalIDFormats, db.Instance().GetFilmByExternalID, w, r)
+	if ok {
+		s.getFilm(id, w, r)
+	}
+}
+
+// @Summary Get actor by external id
+// @Tags actor
+// @Description Поиск актера по идентификатору во внешнем каталоге: imdb (nm0000209), kinopoisk (7418) или wikidata (Q48337)
+// @ID get-actor-by-external-id
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param source path string true "imdb, kinopoisk или wikidata"
+// @Param value path string true "идентификатор"
+// @Success 200 {object} models.ActorRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/by-external/{source}/{value} [get]
+func (s *Server) getActorByExternalID(segments []string, w http.ResponseWriter, r *http.Request) {
+	id, ok := resolveExternalID(segments, actorExternalIDFormats, db.Instance().GetActorByExternalID, w, r)
+	if ok {
+		s.getActor(id, w, r)
+	}
+}
+
+// resolveExternalID finds the record by the /{source}/{value} path segments.
+// On failure the error is written to the response and false is returned.
+func resolveExternalID(segments []string, formats map[models.ExternalSource]*regexp.Regexp, lookup func(models.ExternalSource, string) (int, error), w http.ResponseWriter, r *http.Request) (int, bool) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+		return -1, false
+	}
+	if len(segments) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return -1, false
+	}
+	externalID := models.ExternalID{Source: models.ExternalSource(segments[0]), Value: segments[1]}
+	if msg := validateExternalIDs([]*models.ExternalID{&externalID}, formats); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return -1, false
+	}
+	id, err := lookup(externalID.Source, externalID.Value)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return -1, false
+	}
+	if id < 1 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return -1, false
+	}
+	return id, true
+}
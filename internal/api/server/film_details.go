@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryCode  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// validateFilmExtras checks details, releases and external ids of the film
+// and normalizes currency and country codes to upper case.
+func validateFilmExtras(filmPost *models.FilmPost) string {
+	if details := filmPost.Details; details != nil {
+		if details.Runtime != nil && (*details.Runtime < 1 || *details.Runtime > 1000) {
+			return "film's runtime should be from 1 to 1000 minutes"
+		}
+		for name, money := range map[string]*models.Money{"budget": details.Budget, "box office": details.BoxOffice} {
+			if money == nil {
+				continue
+			}
+			money.Currency = strings.ToUpper(money.Currency)
+			if money.Amount < 0 {
+				return fmt.Sprintf("film's %v should not be negative", name)
+			}
+			if !currencyCode.MatchString(money.Currency) {
+				return fmt.Sprintf("film's %v currency should be an ISO 4217 code", name)
+			}
+		}
+	}
+	countries := map[string]bool{}
+	for _, release := range filmPost.Releases {
+		if release == nil {
+			return "invalid release"
+		}
+		release.Country = strings.ToUpper(release.Country)
+		if !countryCode.MatchString(release.Country) {
+			return "release country should be an ISO 3166-1 alpha-2 code"
+		}
+		if countries[release.Country] {
+			return fmt.Sprintf("duplicate release in %v", release.Country)
+		}
+		countries[release.Country] = true
+		if release.ReleaseDate == nil || release.ReleaseDate.IsZero() {
+			return "release date was not provided"
+		}
+		if release.Certification != nil && (len(*release.Certification) == 0 || len(*release.Certification) > 10) {
+			return "certification length should be at least 1 and no more than 10 characters"
+		}
+	}
+	return validateExternalIDs(filmPost.ExternalIDs, filmExternalIDFormats)
+}
+
+// saveFilmExtras merges provided details into the stored ones and replaces
+// releases and external ids if they were provided.
+func saveFilmExtras(tx *db.DBProvider, filmID int, filmPost *models.FilmPost) error {
+	if filmPost.Details != nil {
+		details, err := tx.GetFilmDetails(filmID)
+		if err != nil {
+			return err
+		}
+		if details == nil {
+			details = &models.FilmDetails{}
+		}
+		err = tx.SetFilmDetails(filmID, details.CopyWith(filmPost.Details))
+		if err != nil {
+			return err
+		}
+	}
+	if filmPost.Releases != nil {
+		err := tx.SetFilmReleases(filmID, filmPost.Releases)
+		if err != nil {
+			return err
+		}
+	}
+	if filmPost.ExternalIDs != nil {
+		return tx.SetFilmExternalIDs(filmID, filmPost.ExternalIDs)
+	}
+	return nil
+}
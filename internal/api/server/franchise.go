@@ -0,0 +1,643 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// FranchiseRules declares permissions required by /franchise/ routes.
+var FranchiseRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/franchise/**", Permission: constants.PermissionFranchisesRead},
+	{Path: "/franchise/**", Permission: constants.PermissionFranchisesWrite},
+}
+
+func (s *Server) FranchiseHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if len(segments) > 2 {
+		if segments[2] != "films" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		s.franchiseFilmsHandler(id, segments[3:], w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if id > 0 {
+			s.getFranchise(id, w, r)
+		} else {
+			s.getFranchises(w, r)
+		}
+	case http.MethodPost:
+		s.postFranchise(w, r)
+	case http.MethodPut:
+		s.putFranchise(id, w, r)
+	case http.MethodDelete:
+		s.deleteFranchise(id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+func (s *Server) franchiseFilmsHandler(franchiseID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if franchiseID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.postFranchiseFilm(franchiseID, w, r)
+	case http.MethodDelete:
+		filmID, err := utils.ParseSegmentID(segments, 0)
+		if err != nil || filmID < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid film id"))
+			return
+		}
+		s.deleteFranchiseFilm(franchiseID, filmID, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+func (s *Server) filmRelationsHandler(filmID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if filmID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.getFilmRelations(filmID, w, r)
+	case http.MethodPost:
+		s.postFilmRelation(filmID, w, r)
+	case http.MethodDelete:
+		relationID, err := utils.ParseSegmentID(segments, 0)
+		if err != nil || relationID < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid relation id"))
+			return
+		}
+		s.deleteFilmRelation(filmID, relationID, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+// @Summary Get franchises
+// @Tags franchise
+// @Description Получение списка франшиз
+// @ID get-franchises
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} models.GetFranchises
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/ [get]
+func (*Server) getFranchises(w http.ResponseWriter, r *http.Request) {
+	franchises, err := db.Instance().GetFranchises()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get franchises"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"franchises": franchises})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Get franchise
+// @Tags franchise
+// @Description Получение франшизы по id вместе со списком фильмов. По умолчанию фильмы упорядочены по позиции в серии, order=release_date упорядочивает по дате выхода
+// @ID get-franchise
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Param order query string false "position или release_date"
+// @Success 200 {object} models.FranchiseRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/{id} [get]
+func (*Server) getFranchise(id int, w http.ResponseWriter, r *http.Request) {
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = constants.FranchiseOrderPosition
+	}
+	if order != constants.FranchiseOrderPosition && order != constants.FranchiseOrderReleaseDate {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("order should be 'position' or 'release_date'"))
+		return
+	}
+	franchise, err := db.Instance().GetFranchise(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get franchise"))
+		return
+	}
+	if franchise == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	films, err := db.Instance().GetFranchiseFilms(id, order)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get films list from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get franchise"))
+		return
+	}
+	localized := []*models.Film{}
+	for _, v := range films {
+		localized = append(localized, v.Film)
+	}
+	err = newLocalizer(w, r).films(localized...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get franchise"))
+		return
+	}
+	res, err := json.Marshal(models.FranchiseRespond{Franchise: franchise, Films: films})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Add franchise
+// @Tags franchise
+// @Description Создание франшизы
+// @ID post-franchise
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param requestBody body models.FranchisePost true "Информация о франшизе"
+// @Success 200 {string} string "franchise added"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/ [post]
+func (*Server) postFranchise(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var franchise models.Franchise
+	err = json.Unmarshal(body, &franchise)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if franchise.Name == nil || len(*franchise.Name) < 1 || len(*franchise.Name) > 150 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("the length of the franchise name must be at least 1 and no more than 150 characters"))
+		return
+	}
+	if franchise.Description != nil && len(*franchise.Description) > 1500 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("franchise's description len should not exceed 1500 symbols"))
+		return
+	}
+	_, err = db.Instance().AddFranchise(&franchise)
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("franchise with this name already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("franchise added"))
+}
+
+// @Summary Update franchise
+// @Tags franchise
+// @Description Изменение франшизы
+// @ID put-franchise
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param requestBody body models.FranchisePost true "Информация о франшизе"
+// @Success 200 {string} string "franchise updated"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/{id} [put]
+func (*Server) putFranchise(id int, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	oldFranchise, err := db.Instance().GetFranchise(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if oldFranchise == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var franchise models.Franchise
+	err = json.Unmarshal(body, &franchise)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if franchise.Name != nil && (len(*franchise.Name) < 1 || len(*franchise.Name) > 150) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("the length of the franchise name must be at least 1 and no more than 150 characters"))
+		return
+	}
+	if franchise.Description != nil && len(*franchise.Description) > 1500 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("franchise's description len should not exceed 1500 symbols"))
+		return
+	}
+	err = db.Instance().UpdateFranchise(oldFranchise.CopyWith(&franchise))
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("franchise with this name already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update franchise: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("franchise updated"))
+}
+
+// @Summary Delete franchise
+// @Tags franchise
+// @Description Удаление франшизы по id, фильмы при этом не удаляются
+// @ID delete-franchise
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Success 200 {string} string "franchise deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/{id} [delete]
+func (*Server) deleteFranchise(id int, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	n, err := db.Instance().DeleteFranchise(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("franchise deleted"))
+}
+
+// @Summary Add film to franchise
+// @Tags franchise
+// @Description Добавление фильма во франшизу на указанную позицию. Если фильм уже входит во франшизу, его позиция меняется
+// @ID post-franchise-film
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id франшизы"
+// @Param requestBody body models.FranchiseFilmPost true "Фильм и его позиция"
+// @Success 200 {string} string "film added to franchise"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/{id}/films [post]
+func (*Server) postFranchiseFilm(franchiseID int, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var entry models.FranchiseFilmPost
+	err = json.Unmarshal(body, &entry)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if entry.FilmID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid film id"))
+		return
+	}
+	if entry.Position < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("position should not be negative"))
+		return
+	}
+	err = db.Instance().SetFranchiseFilm(franchiseID, entry.FilmID, entry.Position)
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("franchise or film not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add film to franchise: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("film added to franchise"))
+}
+
+// @Summary Remove film from franchise
+// @Tags franchise
+// @Description Исключение фильма из франшизы
+// @ID delete-franchise-film
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id франшизы"
+// @Param film_id path int true "id фильма"
+// @Success 200 {string} string "film removed from franchise"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /franchise/{id}/films/{film_id} [delete]
+func (*Server) deleteFranchiseFilm(franchiseID int, filmID int, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().DeleteFranchiseFilm(franchiseID, filmID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("film removed from franchise"))
+}
+
+// @Summary Get film relations
+// @Tags film
+// @Description Получение связей фильма с другими фильмами. outgoing - связи, где фильм является сиквелом/приквелом/ремейком/спин-оффом другого фильма, incoming - обратные связи
+// @ID get-film-relations
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Param type query string false "sequel, prequel, remake или spin_off"
+// @Param direction query string false "outgoing или incoming"
+// @Success 200 {object} models.GetFilmRelations
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/relations [get]
+func (*Server) getFilmRelations(filmID int, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	relType := models.RelationType(query.Get("type"))
+	if relType != "" && !isValidRelationType(relType) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("type should be 'sequel', 'prequel', 'remake' or 'spin_off'"))
+		return
+	}
+	direction := models.RelationDirection(query.Get("direction"))
+	if direction != "" && direction != constants.DirectionOutgoing && direction != constants.DirectionIncoming {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("direction should be 'outgoing' or 'incoming'"))
+		return
+	}
+	film, err := db.Instance().GetFilm(filmID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if film.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	relations, err := db.Instance().GetFilmRelations(filmID, relType, direction)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get relations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	localized := []*models.Film{}
+	for _, v := range relations {
+		localized = append(localized, v.Film)
+	}
+	err = newLocalizer(w, r).films(localized...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"relations": relations})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Add film relation
+// @Tags film
+// @Description Создание связи: фильм с указанным id является сиквелом/приквелом/ремейком/спин-оффом фильма related_film_id
+// @ID post-film-relation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param requestBody body models.FilmRelationPost true "Связанный фильм и тип связи"
+// @Success 200 {string} string "relation added"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/relations [post]
+func (*Server) postFilmRelation(filmID int, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	var relationPost models.FilmRelationPost
+	err = json.Unmarshal(body, &relationPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if !isValidRelationType(relationPost.Type) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("type should be 'sequel', 'prequel', 'remake' or 'spin_off'"))
+		return
+	}
+	if relationPost.RelatedFilmID < 1 || relationPost.RelatedFilmID == filmID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid related film id"))
+		return
+	}
+	relation := models.FilmRelation{FilmID: filmID, RelatedFilmID: relationPost.RelatedFilmID, Type: relationPost.Type}
+	_, err = db.Instance().AddFilmRelation(&relation)
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("relation already exists"))
+		return
+	}
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("film not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("relation added"))
+}
+
+// @Summary Delete film relation
+// @Tags film
+// @Description Удаление связи между фильмами
+// @ID delete-film-relation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id фильма"
+// @Param relation_id path int true "id связи"
+// @Success 200 {string} string "relation deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/relations/{relation_id} [delete]
+func (*Server) deleteFilmRelation(filmID int, relationID int, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().DeleteFilmRelation(filmID, relationID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("relation deleted"))
+}
+
+func isValidRelationType(relType models.RelationType) bool {
+	switch relType {
+	case constants.RelationSequel, constants.RelationPrequel, constants.RelationRemake, constants.RelationSpinOff:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/imaging"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+func (s *Server) imageHandler(entity models.ImageEntity, id int, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.getImage(entity, id, w, r)
+	case http.MethodPost:
+		s.uploadImage(entity, id, w, r)
+	case http.MethodDelete:
+		s.deleteImage(entity, id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+// @Summary Get film poster
+// @Tags film
+// @Description Ссылки на постер фильма и его уменьшенные копии
+// @ID get-film-poster
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.ImageURLs
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/poster [get]
+func (s *Server) getImage(entity models.ImageEntity, id int, w http.ResponseWriter, r *http.Request) {
+	urls, err := s.imageURLs(entity, id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get image from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if urls == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	res, err := json.Marshal(urls)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Upload film poster
+// @Tags film
+// @Description Загрузка постера фильма (jpeg, png или gif) в поле image формы multipart/form-data. Уменьшенные копии создаются автоматически, предыдущий постер заменяется. Для фото актера используется POST /actor/{id}/photo
+// @ID post-film-poster
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Param id path int true "id"
+// @Param image formData file true "изображение"
+// @Success 200 {string} string "image uploaded"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 413 {string} string "image is too large"
+// @Failure 415 {string} string "unsupported image type"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/poster [post]
+func (s *Server) uploadImage(entity models.ImageEntity, id int, w http.ResponseWriter, r *http.Request) {
+	exists, err := entityExists(entity, id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxImageSize+(1<<20))
+	file, _, err := r.FormFile(constants.ImageFormField)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("image is too large"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get image from form: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("image should be sent as multipart/form-data field '%v'", constants.ImageFormField)))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, constants.MaxImageSize+1))
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read image: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot read image"))
+		return
+	}
+	if len(data) > constants.MaxImageSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("image is too large"))
+		return
+	}
+
+	// The client supplied Content-Type is not trusted, the type is sniffed
+	// from the content instead.
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		w.Write([]byte("unsupported image type, expected jpeg, png or gif"))
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot decode image"))
+		return
+	}
+	if config.Width > constants.MaxImageDimension || config.Height > constants.MaxImageDimension {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("image width and height should not exceed %v pixels", constants.MaxImageDimension)))
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot decode image"))
+		return
+	}
+
+	token := make([]byte, 8)
+	_, err = rand.Read(token)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate image key: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	record := models.Image{
+		Entity:      entity,
+		EntityID:    id,
+		KeyPrefix:   fmt.Sprintf("%vs/%v/%v", entity, id, hex.EncodeToString(token)),
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+	original, thumbnails := imageKeys(&record)
+	err = s.Store.Put(original, bytes.NewReader(data))
+	for name, size := range constants.ThumbnailSizes {
+		if err != nil {
+			break
+		}
+		var buf bytes.Buffer
+		err = imaging.Encode(&buf, imaging.Thumbnail(img, size), contentType)
+		if err == nil {
+			err = s.Store.Put(thumbnails[name], &buf)
+		}
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot store image: %v", r.Method, r.RequestURI, err)
+		s.removeImageBlobs(&record)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+
+	old, err := db.Instance().SetImage(&record)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		s.removeImageBlobs(&record)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if old != nil {
+		s.removeImageBlobs(old)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("image uploaded"))
+}
+
+// @Summary Delete film poster
+// @Tags film
+// @Description Удаление постера фильма
+// @ID delete-film-poster
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Success 200 {string} string "image deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/poster [delete]
+func (s *Server) deleteImage(entity models.ImageEntity, id int, w http.ResponseWriter, r *http.Request) {
+	image, err := db.Instance().DeleteImage(entity, id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if image == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	s.removeImageBlobs(image)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("image deleted"))
+}
+
+// removeImage drops the image of a deleted film or actor. Failures are only
+// logged since the entity itself is already gone.
+func (s *Server) removeImage(entity models.ImageEntity, id int) {
+	image, err := db.Instance().DeleteImage(entity, id)
+	if err != nil {
+		log.Printf("ERROR cannot delete %v %v image: %v", entity, id, err)
+		return
+	}
+	if image != nil {
+		s.removeImageBlobs(image)
+	}
+}
+
+func (s *Server) removeImageBlobs(image *models.Image) {
+	original, thumbnails := imageKeys(image)
+	keys := []string{original}
+	for _, key := range thumbnails {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		err := s.Store.Delete(key)
+		if err != nil {
+			log.Printf("ERROR cannot delete blob %v: %v", key, err)
+		}
+	}
+}
+
+func (s *Server) imageURLs(entity models.ImageEntity, id int) (*models.ImageURLs, error) {
+	image, err := db.Instance().GetImage(entity, id)
+	if err != nil || image == nil {
+		return nil, err
+	}
+	original, thumbnails := imageKeys(image)
+	res := models.ImageURLs{
+		Original:   s.Store.URL(original),
+		Width:      image.Width,
+		Height:     image.Height,
+		Thumbnails: map[string]string{},
+	}
+	for name, key := range thumbnails {
+		res.Thumbnails[name] = s.Store.URL(key)
+	}
+	return &res, nil
+}
+
+// imageKeys returns blob keys of the original upload and of every thumbnail.
+// Thumbnails of jpeg images are jpeg, all other are encoded as png.
+func imageKeys(image *models.Image) (string, map[string]string) {
+	thumbnailExt := ".png"
+	if image.ContentType == "image/jpeg" {
+		thumbnailExt = ".jpg"
+	}
+	thumbnails := map[string]string{}
+	for name := range constants.ThumbnailSizes {
+		thumbnails[name] = image.KeyPrefix + "/" + name + thumbnailExt
+	}
+	return image.KeyPrefix + "/original" + imageExtensions[image.ContentType], thumbnails
+}
+
+func entityExists(entity models.ImageEntity, id int) (bool, error) {
+	if entity == constants.ImageEntityFilm {
+		return db.Instance().FilmExists(id)
+	}
+	return db.Instance().ActorExists(id)
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// MeHandler serves the account of the current user.
+func (s *Server) MeHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		s.getProfile(w, r)
+	case len(segments) == 1 && r.Method == http.MethodPut:
+		s.putProfile(w, r)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		s.deleteAccount(w, r)
+	case len(segments) == 2 && segments[1] == "export" && r.Method == http.MethodGet:
+		s.exportPersonalData(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get profile
+// @Tags me
+// @Description Учетная запись текущего пользователя: логин, отображаемое имя, роль и права роли
+// @ID get-profile
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.Profile
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /me [get]
+func (*Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := currentProfile(w, r)
+	if !ok {
+		return
+	}
+	writeUserJSON(profile, w, r)
+}
+
+// @Summary Update profile
+// @Tags me
+// @Description Изменение отображаемого имени текущего пользователя. Пустое имя удаляет его
+// @ID put-profile
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param requestBody body models.ProfilePut true "Отображаемое имя"
+// @Success 200 {object} models.Profile
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /me [put]
+func (*Server) putProfile(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	profilePut := models.ProfilePut{}
+	err = json.Unmarshal(body, &profilePut)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if profilePut.DisplayName == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("display_name was not provided"))
+		return
+	}
+	displayName := strings.TrimSpace(*profilePut.DisplayName)
+	if len([]rune(displayName)) > 100 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("display_name length should be no more than 100 characters"))
+		return
+	}
+	var value *string
+	if displayName != "" {
+		value = &displayName
+	}
+	err = db.Instance().SetDisplayName(middleware.CurrentUser(r).ID, value)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	profile, ok := currentProfile(w, r)
+	if !ok {
+		return
+	}
+	writeUserJSON(profile, w, r)
+}
+
+// @Summary Delete account
+// @Tags me
+// @Description Удаление учетной записи текущего пользователя вместе с ее токенами, сессиями и API ключами. Требуется пароль. Авторство ревизий сохраняется. Последний активный администратор удалить себя не может
+// @ID delete-account
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Param requestBody body models.AccountDelete true "Пароль"
+// @Success 200 {string} string "account deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 429 {string} string "too many failed login attempts"
+// @Failure 500 {string} string "internal server error"
+// @Router /me [delete]
+func (s *Server) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.AccountDelete{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	user, err := auth.Login(middleware.CurrentUser(r).Name, request.Password, auth.ClientIP(r))
+	var lockedErr *auth.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", lockedErr.RetryAfterSeconds())
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(lockedErr.Error()))
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("password is wrong"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if user.Role == constants.AdminRole {
+		enabled := false
+		admins, err := db.Instance().GetUsers(&models.UsersFilter{Role: constants.AdminRole, Disabled: &enabled})
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get users from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if len(admins) <= 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("cannot delete the last admin"))
+			return
+		}
+	}
+	_, err = db.Instance().DeleteUser(user.ID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	log.Printf("%v deleted own account", user.Name)
+	if middleware.CurrentSession(r) != nil {
+		s.setSessionCookies(w, "", "", time.Time{})
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("account deleted"))
+}
+
+// @Summary Export personal data
+// @Tags me
+// @Description Выгрузка всех данных текущего пользователя одним JSON файлом: учетная запись, активные сессии, API ключи и ревизии, автором которых он является
+// @ID export-personal-data
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.PersonalDataExport
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /me/export [get]
+func (*Server) exportPersonalData(w http.ResponseWriter, r *http.Request) {
+	profile, ok := currentProfile(w, r)
+	if !ok {
+		return
+	}
+	user := middleware.CurrentUser(r)
+	export := models.PersonalDataExport{ExportedAt: time.Now().UTC(), Account: profile}
+	var err error
+	export.Sessions, err = db.Instance().GetSessions(user.ID, time.Now().Add(-constants.SessionIdleTimeout))
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get sessions from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	export.APIKeys, err = db.Instance().GetAPIKeys(user.ID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get api keys from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	export.Revisions, err = db.Instance().GetAuthorRevisions(user.Name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get revisions from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": user.Name + "-export.json"}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// currentProfile writes 401 if the current user does not exist anymore.
+func currentProfile(w http.ResponseWriter, r *http.Request) (*models.Profile, bool) {
+	user := middleware.CurrentUser(r)
+	info, err := db.Instance().GetUserInfo(user.Name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return nil, false
+	}
+	if info == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("unauthorized"))
+		return nil, false
+	}
+	permissions, err := auth.RolePermissions(info.Role)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get roles from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return nil, false
+	}
+	return &models.Profile{
+		Name:              info.Name,
+		DisplayName:       info.DisplayName,
+		Role:              info.Role,
+		Permissions:       permissions,
+		CreatedAt:         info.CreatedAt,
+		MustResetPassword: info.MustResetPassword,
+	}, true
+}
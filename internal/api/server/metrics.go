@@ -0,0 +1,35 @@
+package server
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+)
+
+// MetricsRules declares permissions required by /debug/vars.
+var MetricsRules = []middleware.Rule{
+	{Path: "/debug/vars", Permission: constants.PermissionMetricsRead},
+}
+
+// @Summary Get metrics
+// @Tags metrics
+// @Description Метрики процесса в формате expvar. credential_cache содержит число попаданий и промахов кэша проверок паролей, число вытесненных записей и текущий размер кэша. Требуется право metrics:read
+// @ID get-metrics
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} object
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Router /debug/vars [get]
+func (*Server) Metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unexpected method"))
+		return
+	}
+	expvar.Handler().ServeHTTP(w, r)
+}
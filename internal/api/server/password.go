@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// @Summary Change password
+// @Tags auth
+// @Description Смена пароля текущего пользователя. Требуется текущий пароль. После смены refresh токены отзываются, выданные ранее access токены перестают приниматься. Доступно и пользователям, которым администратор потребовал сменить пароль
+// @ID change-password
+// @Accept json
+// @Produce plain
+// @Param requestBody body models.ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 200 {string} string "password changed"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 403 {string} string "account is disabled"
+// @Failure 429 {string} string "too many failed login attempts"
+// @Failure 500 {string} string "internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /auth/password [post]
+func (s *Server) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unexpected method"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.ChangePasswordRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if request.NewPassword == request.CurrentPassword {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("new password should differ from the current one"))
+		return
+	}
+	if err := auth.ValidatePassword(request.NewPassword); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	// The current password is checked even for bearer tokens and API keys,
+	// failures count towards the lockout like any other login.
+	user, err := auth.Login(middleware.CurrentUser(r).Name, request.CurrentPassword, auth.ClientIP(r))
+	var lockedErr *auth.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", lockedErr.RetryAfterSeconds())
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(lockedErr.Error()))
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("current password is wrong"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	hashedPass, err := auth.HashPassword(request.NewPassword)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate hash for pass: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	err = db.Instance().SetUserPassword(user.ID, hashedPass)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot set user password in db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password changed"))
+}
+
+// @Summary Request password reset
+// @Tags auth
+// @Description Запрос сброса забытого пароля. Одноразовый токен сброса отправляется пользователю через настроенный канал уведомлений и действует час. Ответ не зависит от того, существует ли пользователь
+// @ID request-password-reset
+// @Accept json
+// @Produce plain
+// @Param requestBody body models.PasswordResetRequest true "Логин"
+// @Success 200 {string} string "password reset requested"
+// @Failure 400 {string} string "error string"
+// @Failure 500 {string} string "internal server error"
+// @Router /auth/password-reset [post]
+func (s *Server) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.PasswordResetRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	user, err := db.Instance().GetUser(request.Name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	// Unknown, disabled and recently reset users get the same answer, so the
+	// endpoint can not be used to find out which logins exist.
+	if user != nil && !user.Disabled {
+		err = s.sendPasswordReset(user)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot send password reset: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password reset requested"))
+}
+
+func (s *Server) sendPasswordReset(user *models.User) error {
+	now := time.Now()
+	recent, err := db.Instance().HasRecentPasswordResetToken(user.ID, now.Add(-constants.PasswordResetRequestInterval))
+	if err != nil || recent {
+		return err
+	}
+	token, tokenHash, err := auth.NewPasswordResetToken()
+	if err != nil {
+		return err
+	}
+	err = db.Instance().AddPasswordResetToken(user.ID, tokenHash, now.Add(constants.PasswordResetTokenTTL))
+	if err != nil {
+		return err
+	}
+	return s.Notifier.Notify(
+		user,
+		"Password reset",
+		fmt.Sprintf(
+			"Use this token to set a new password with POST /auth/password-reset/confirm:\n\n%v\n\nIt is valid for %v and can be used once. If you did not request a reset, ignore this message.",
+			token,
+			constants.PasswordResetTokenTTL,
+		),
+	)
+}
+
+// @Summary Confirm password reset
+// @Tags auth
+// @Description Установка нового пароля по токену сброса. Токен одноразовый. После сброса отзываются все refresh токены, API ключи и остальные токены сброса пользователя, выданные ранее access токены перестают приниматься, блокировка входа снимается
+// @ID confirm-password-reset
+// @Accept json
+// @Produce plain
+// @Param requestBody body models.PasswordResetConfirm true "Токен сброса и новый пароль"
+// @Success 200 {string} string "password reset"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "invalid reset token"
+// @Failure 500 {string} string "internal server error"
+// @Router /auth/password-reset/confirm [post]
+func (s *Server) confirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.PasswordResetConfirm{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if err := auth.ValidatePassword(request.NewPassword); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	hashedPass, err := auth.HashPassword(request.NewPassword)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot generate hash for pass: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	userID, err := db.Instance().ResetPassword(auth.HashToken(request.Token), hashedPass)
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("invalid reset token"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot reset password in db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(userID)
+	user, err := db.Instance().GetUserByID(userID)
+	if err == nil && user != nil {
+		err = auth.Unlock(user.Name)
+	}
+	if err != nil {
+		// The password is already reset, a remaining lock expires on its own.
+		log.Printf("ERROR %v %v: cannot unlock user: %v", r.Method, r.RequestURI, err)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password reset"))
+}
@@ -0,0 +1,661 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// ProposalRules declares permissions required by /proposal/ routes.
+var ProposalRules = []middleware.Rule{
+	{Path: "/proposal/**", Permission: constants.PermissionProposalsSubmit},
+}
+
+// ModerationRules declares permissions required by /moderation/ routes.
+var ModerationRules = []middleware.Rule{
+	{Path: "/moderation/**", Permission: constants.PermissionProposalsModerate},
+}
+
+func (s *Server) ProposalHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.getOwnProposals(w, r)
+		case http.MethodPost:
+			s.postProposal(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Unexpected HTTP method"))
+		}
+		return
+	}
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	if len(segments) == 2 && r.Method == http.MethodGet {
+		s.getOwnProposal(id, w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("not found"))
+}
+
+func (s *Server) ModerationHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) == 1 && r.Method == http.MethodGet {
+		s.getModerationQueue(w, r)
+		return
+	}
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch {
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		s.getProposalDiff(id, w, r)
+	case len(segments) == 3 && segments[2] == "approve" && r.Method == http.MethodPost:
+		s.approveProposal(id, w, r)
+	case len(segments) == 3 && segments[2] == "reject" && r.Method == http.MethodPost:
+		s.rejectProposal(id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Add proposal
+// @Tags proposal
+// @Description Предложение создать или изменить фильм или актера либо изменить актерский состав фильма. Предложение попадает в очередь модерации и применяется после одобрения модератором. payload имеет формат тела POST /film/ или PUT /film/{id} для фильмов, POST /actor/ или PUT /actor/{id} для актеров и models.CastProposal для состава. Требуется право proposals:submit
+// @ID post-proposal
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param requestBody body models.ProposalPost true "Сущность, действие, id изменяемой записи и тело запроса"
+// @Success 201 {object} models.Proposal
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /proposal/ [post]
+func (*Server) postProposal(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	proposalPost := models.ProposalPost{}
+	err = json.Unmarshal(body, &proposalPost)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	if msg := validateProposalPost(&proposalPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	if proposalPost.EntityID != nil {
+		exists, err := proposalTargetExists(proposalPost.Entity, *proposalPost.EntityID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+	}
+	proposal := models.Proposal{
+		Entity:   proposalPost.Entity,
+		EntityID: proposalPost.EntityID,
+		Action:   proposalPost.Action,
+		Payload:  proposalPost.Payload,
+		Comment:  proposalPost.Comment,
+		Status:   constants.ProposalPending,
+		Author:   middleware.CurrentUser(r).Name,
+	}
+	proposal.ID, err = db.Instance().AddProposal(&proposal)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add proposal to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	created, err := db.Instance().GetProposal(proposal.ID)
+	if err != nil || created == nil {
+		log.Printf("ERROR %v %v: cannot get proposal from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeProposalJSON(created, http.StatusCreated, w, r)
+}
+
+// validateProposalPost checks the kind of the proposal and that the payload
+// has the format of the request applying it. The payload itself is validated
+// when the proposal is applied.
+func validateProposalPost(proposalPost *models.ProposalPost) string {
+	switch {
+	case proposalPost.Entity != constants.RevisionEntityFilm && proposalPost.Entity != constants.RevisionEntityActor && proposalPost.Entity != constants.RevisionEntityCast:
+		return "entity should be film, actor or cast"
+	case proposalPost.Action != constants.RevisionCreate && proposalPost.Action != constants.RevisionUpdate:
+		return "action should be create or update"
+	case proposalPost.Entity == constants.RevisionEntityCast && proposalPost.Action != constants.RevisionUpdate:
+		return "cast proposals should be updates"
+	case proposalPost.Action == constants.RevisionCreate && proposalPost.EntityID != nil:
+		return "entity_id should not be provided for creates"
+	case proposalPost.Action == constants.RevisionUpdate && (proposalPost.EntityID == nil || *proposalPost.EntityID < 1):
+		return "entity_id should be provided for updates"
+	case proposalPost.Comment != nil && len([]rune(*proposalPost.Comment)) > 500:
+		return "comment length should be no more than 500 characters"
+	case len(proposalPost.Payload) == 0 || bytes.Equal(proposalPost.Payload, []byte("null")):
+		return "payload was not provided"
+	}
+	var payload any
+	switch proposalPost.Entity {
+	case constants.RevisionEntityFilm:
+		payload = &models.FilmPut{}
+	case constants.RevisionEntityActor:
+		payload = &models.Actor{}
+	case constants.RevisionEntityCast:
+		payload = &models.CastProposal{}
+	}
+	if err := json.Unmarshal(proposalPost.Payload, payload); err != nil {
+		return fmt.Sprintf("invalid payload: %v", err)
+	}
+	if cast, ok := payload.(*models.CastProposal); ok && len(cast.ActorsList) == 0 && len(cast.RemoveActors) == 0 {
+		return "cast proposal should add or remove actors"
+	}
+	return ""
+}
+
+// proposalTargetExists reports whether the film or the actor to update
+// exists. Casts are changed through their film.
+func proposalTargetExists(entity models.RevisionEntity, id int) (bool, error) {
+	if entity == constants.RevisionEntityActor {
+		actor, err := db.Instance().GetActor(id)
+		return err == nil && actor.ID != 0, err
+	}
+	film, err := db.Instance().GetFilm(id)
+	return err == nil && film.ID != 0, err
+}
+
+// @Summary Get own proposals
+// @Tags proposal
+// @Description Предложения текущего пользователя и их статус: pending, approved или rejected с причиной отказа. Требуется право proposals:submit
+// @ID get-own-proposals
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, approved или rejected"
+// @Success 200 {object} models.GetProposals
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /proposal/ [get]
+func (*Server) getOwnProposals(w http.ResponseWriter, r *http.Request) {
+	filter, ok := proposalsFilter(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid filter"))
+		return
+	}
+	filter.Author = middleware.CurrentUser(r).Name
+	writeProposals(filter, w, r)
+}
+
+// @Summary Get own proposal
+// @Tags proposal
+// @Description Предложение текущего пользователя по id. Требуется право proposals:submit
+// @ID get-own-proposal
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.Proposal
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /proposal/{id} [get]
+func (*Server) getOwnProposal(id int, w http.ResponseWriter, r *http.Request) {
+	proposal, ok := findProposal(id, w, r)
+	if !ok {
+		return
+	}
+	if proposal.Author != middleware.CurrentUser(r).Name {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("proposal not found"))
+		return
+	}
+	writeProposalJSON(proposal, http.StatusOK, w, r)
+}
+
+// @Summary Get moderation queue
+// @Tags moderation
+// @Description Очередь предложений, сначала самые старые. По умолчанию показываются только ожидающие решения. Требуется право proposals:moderate
+// @ID get-moderation-queue
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending (по умолчанию), approved или rejected"
+// @Param entity query string false "film, actor или cast"
+// @Param author query string false "логин автора"
+// @Success 200 {object} models.GetProposals
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /moderation/ [get]
+func (*Server) getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	filter, ok := proposalsFilter(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid filter"))
+		return
+	}
+	if filter.Status == "" {
+		filter.Status = constants.ProposalPending
+	}
+	filter.Author = r.URL.Query().Get("author")
+	writeProposals(filter, w, r)
+}
+
+// proposalsFilter parses status and entity from the query, false means that
+// one of them is invalid.
+func proposalsFilter(r *http.Request) (*models.ProposalsFilter, bool) {
+	query := r.URL.Query()
+	filter := models.ProposalsFilter{
+		Status: models.ProposalStatus(query.Get("status")),
+		Entity: models.RevisionEntity(query.Get("entity")),
+	}
+	switch filter.Status {
+	case "", constants.ProposalPending, constants.ProposalApproved, constants.ProposalRejected:
+	default:
+		return nil, false
+	}
+	switch filter.Entity {
+	case "", constants.RevisionEntityFilm, constants.RevisionEntityActor, constants.RevisionEntityCast:
+	default:
+		return nil, false
+	}
+	return &filter, true
+}
+
+func writeProposals(filter *models.ProposalsFilter, w http.ResponseWriter, r *http.Request) {
+	proposals, err := db.Instance().GetProposals(filter)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get proposals from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeProposalJSON(models.GetProposals{Proposals: proposals}, http.StatusOK, w, r)
+}
+
+// @Summary Get proposal diff
+// @Tags moderation
+// @Description Предложение и сравнение текущей записи с записью после его применения. Для фильмов сравниваются поля фильма и актерский состав, для актеров поля актера. Остальные изменения видны в payload. Требуется право proposals:moderate
+// @ID get-proposal-diff
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.ProposalDiff
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /moderation/{id} [get]
+func (*Server) getProposalDiff(id int, w http.ResponseWriter, r *http.Request) {
+	proposal, ok := findProposal(id, w, r)
+	if !ok {
+		return
+	}
+	diff, err := proposalDiff(proposal)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot diff proposal: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if diff == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("%v %v not found", proposal.Entity, *proposal.EntityID)))
+		return
+	}
+	writeProposalJSON(diff, http.StatusOK, w, r)
+}
+
+// filmState is the film with its cast as compared in proposal diffs.
+type filmState struct {
+	*models.Film
+	ActorsIDs []int `json:"actors_ids"`
+}
+
+// proposalDiff returns nil if the record the proposal updates does not exist
+// anymore.
+func proposalDiff(proposal *models.Proposal) (*models.ProposalDiff, error) {
+	var current, proposed any
+	switch {
+	case proposal.Entity == constants.RevisionEntityActor:
+		actor := models.Actor{}
+		err := json.Unmarshal(proposal.Payload, &actor)
+		if err != nil {
+			return nil, err
+		}
+		proposed = actorSnapshot(&actor)
+		if proposal.EntityID != nil {
+			old, err := db.Instance().GetActor(*proposal.EntityID)
+			if err != nil || old.ID == 0 {
+				return nil, err
+			}
+			current, proposed = actorSnapshot(old), actorSnapshot(old.CopyWith(&actor))
+		}
+	case proposal.EntityID == nil:
+		filmPost := models.FilmPost{}
+		err := json.Unmarshal(proposal.Payload, &filmPost)
+		if err != nil {
+			return nil, err
+		}
+		proposed = &filmState{Film: &filmPost.Film, ActorsIDs: sortedIDs(filmPost.ActorsList)}
+	default:
+		filmPut := models.FilmPut{}
+		err := json.Unmarshal(proposal.Payload, &filmPut)
+		if err != nil {
+			return nil, err
+		}
+		old, err := db.Instance().GetFilm(*proposal.EntityID)
+		if err != nil || old.ID == 0 {
+			return nil, err
+		}
+		actors, err := db.Instance().GetFilmActors(old.ID)
+		if err != nil {
+			return nil, err
+		}
+		cast := map[int]bool{}
+		for _, actor := range actors {
+			cast[actor.ID] = true
+		}
+		currentCast := castIDs(cast)
+		for _, actorID := range filmPut.ActorsList {
+			cast[actorID] = true
+		}
+		for _, actorID := range filmPut.RemoveActors {
+			delete(cast, actorID)
+		}
+		current = &filmState{Film: old, ActorsIDs: currentCast}
+		proposed = &filmState{Film: old.CopyWith(&filmPut.Film), ActorsIDs: castIDs(cast)}
+	}
+	from := json.RawMessage("{}")
+	if current != nil {
+		var err error
+		from, err = json.Marshal(current)
+		if err != nil {
+			return nil, err
+		}
+	}
+	to, err := json.Marshal(proposed)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffSnapshots(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ProposalDiff{Proposal: proposal, Current: current, Proposed: proposed, Changes: changes}, nil
+}
+
+func castIDs(cast map[int]bool) []int {
+	res := []int{}
+	for actorID := range cast {
+		res = append(res, actorID)
+	}
+	sort.Ints(res)
+	return res
+}
+
+func sortedIDs(ids []int) []int {
+	res := append([]int{}, ids...)
+	sort.Ints(res)
+	return res
+}
+
+// @Summary Approve proposal
+// @Tags moderation
+// @Description Одобрение предложения. Изменение применяется обычным запросом создания или изменения от имени модератора, с теми же проверками. Если запрос не прошел проверки, предложение остается в очереди, а ответ запроса возвращается как есть. force=true создает запись, даже если найдены вероятные дубликаты. Автор получает уведомление. Требуется право proposals:moderate
+// @ID approve-proposal
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param id path int true "id"
+// @Param force query bool false "создать запись, даже если найдены вероятные дубликаты"
+// @Success 200 {string} string "proposal approved"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 409 {string} string "proposal has already been reviewed"
+// @Failure 500 {string} string "internal server error"
+// @Router /moderation/{id}/approve [post]
+func (s *Server) approveProposal(id int, w http.ResponseWriter, r *http.Request) {
+	proposal, ok := findProposal(id, w, r)
+	if !ok {
+		return
+	}
+	moderator := middleware.CurrentUser(r).Name
+	// The proposal is marked first, so that concurrent approvals can not
+	// apply it twice.
+	n, err := db.Instance().ReviewProposal(id, constants.ProposalApproved, moderator, nil)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update proposal: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("proposal has already been reviewed"))
+		return
+	}
+	result := s.applyProposal(proposal, r)
+	if result.status != http.StatusOK {
+		err := db.Instance().ReopenProposal(id)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot reopen proposal: %v", r.Method, r.RequestURI, err)
+		}
+		for key, values := range result.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(result.status)
+		w.Write(result.body.Bytes())
+		return
+	}
+	log.Printf("proposal %v by %v approved by %v", id, proposal.Author, moderator)
+	s.notifyProposalAuthor(proposal, "Proposal approved", fmt.Sprintf("Your proposal %v has been approved by %v.", id, moderator), r)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("proposal approved"))
+}
+
+// @Summary Reject proposal
+// @Tags moderation
+// @Description Отклонение предложения с указанием причины. Автор получает уведомление. Требуется право proposals:moderate
+// @ID reject-proposal
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param requestBody body models.ProposalReject true "Причина отказа"
+// @Success 200 {string} string "proposal rejected"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 409 {string} string "proposal has already been reviewed"
+// @Failure 500 {string} string "internal server error"
+// @Router /moderation/{id}/reject [post]
+func (s *Server) rejectProposal(id int, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	reject := models.ProposalReject{}
+	err = json.Unmarshal(body, &reject)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	reason := strings.TrimSpace(reject.Reason)
+	if len(reason) == 0 || len([]rune(reason)) > 500 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("reason length should be at least 1 and no more than 500 characters"))
+		return
+	}
+	proposal, ok := findProposal(id, w, r)
+	if !ok {
+		return
+	}
+	moderator := middleware.CurrentUser(r).Name
+	n, err := db.Instance().ReviewProposal(id, constants.ProposalRejected, moderator, &reason)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update proposal: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("proposal has already been reviewed"))
+		return
+	}
+	s.notifyProposalAuthor(proposal, "Proposal rejected", fmt.Sprintf("Your proposal %v has been rejected by %v: %v", id, moderator, reason), r)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("proposal rejected"))
+}
+
+// proposalResult records the response of the request applying a proposal.
+type proposalResult struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (res *proposalResult) Header() http.Header {
+	return res.header
+}
+
+func (res *proposalResult) Write(data []byte) (int, error) {
+	if res.status == 0 {
+		res.status = http.StatusOK
+	}
+	return res.body.Write(data)
+}
+
+func (res *proposalResult) WriteHeader(status int) {
+	if res.status == 0 {
+		res.status = status
+	}
+}
+
+// applyProposal replays the proposal as the create or update request it
+// stands for, made by the current user. Casts are changed by updating the
+// film.
+func (s *Server) applyProposal(proposal *models.Proposal, r *http.Request) *proposalResult {
+	handler := s.FilmHandler
+	target := "/film/"
+	if proposal.Entity == constants.RevisionEntityActor {
+		handler = s.ActorHandler
+		target = "/actor/"
+	}
+	method := http.MethodPost
+	if proposal.EntityID != nil {
+		method = http.MethodPut
+		target += fmt.Sprint(*proposal.EntityID)
+	} else if isForced(r) {
+		target += "?force=true"
+	}
+	result := &proposalResult{header: http.Header{}}
+	req, err := http.NewRequestWithContext(r.Context(), method, target, bytes.NewReader(proposal.Payload))
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot apply proposal %v: %v", r.Method, r.RequestURI, proposal.ID, err)
+		result.WriteHeader(http.StatusInternalServerError)
+		result.Write([]byte("internal server error"))
+		return result
+	}
+	req.RequestURI = target
+	req.RemoteAddr = r.RemoteAddr
+	handler(result, req)
+	return result
+}
+
+func (s *Server) notifyProposalAuthor(proposal *models.Proposal, subject string, body string, r *http.Request) {
+	author, err := db.Instance().GetUser(proposal.Author)
+	if err == nil && author != nil {
+		err = s.Notifier.Notify(author, subject, body)
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot notify author of proposal %v: %v", r.Method, r.RequestURI, proposal.ID, err)
+	}
+}
+
+// findProposal writes 404 if there is no proposal with the id.
+func findProposal(id int, w http.ResponseWriter, r *http.Request) (*models.Proposal, bool) {
+	proposal, err := db.Instance().GetProposal(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get proposal from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return nil, false
+	}
+	if proposal == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("proposal not found"))
+		return nil, false
+	}
+	return proposal, true
+}
+
+func writeProposalJSON(respond any, status int, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(res)
+}
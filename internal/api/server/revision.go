@@ -0,0 +1,462 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// recordRevision stores the change made by the authenticated user. It should
+// be called in the transaction of the change, so that the change is not
+// committed without its revision.
+func recordRevision(tx *db.DBProvider, r *http.Request, entity models.RevisionEntity, id int, action models.RevisionAction, before any, after any) error {
+	revision := models.Revision{
+		Entity:   entity,
+		EntityID: id,
+		Action:   action,
+		Author:   middleware.CurrentUser(r).Name,
+	}
+	var err error
+	revision.Before, err = snapshot(before)
+	if err == nil {
+		revision.After, err = snapshot(after)
+	}
+	if err == nil {
+		err = tx.AddRevision(&revision)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot record revision of %v %v: %w", entity, id, err)
+	}
+	return nil
+}
+
+func snapshot(value any) (json.RawMessage, error) {
+	if value == nil || reflect.ValueOf(value).IsNil() {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+// actorSnapshot leaves out of the actor everything that is computed or stored
+// apart from the actors table.
+func actorSnapshot(actor *models.Actor) *models.Actor {
+	return &models.Actor{
+		ID:        actor.ID,
+		FirstName: actor.FirstName,
+		LastName:  actor.LastName,
+		Sex:       actor.Sex,
+		Birthdate: actor.Birthdate,
+	}
+}
+
+func castSnapshot(filmID int, actorID int) *models.CastLink {
+	return &models.CastLink{FilmID: filmID, ActorID: actorID}
+}
+
+// loadFilmSnapshot reads the film together with everything saved along with
+// it, so that revisions show and revert changes of any of it. Nil means that
+// the film does not exist.
+func loadFilmSnapshot(tx *db.DBProvider, id int) (*models.FilmSnapshot, error) {
+	film, err := tx.GetFilm(id)
+	if err != nil || film.ID == 0 {
+		return nil, err
+	}
+	res := models.FilmSnapshot{Film: *film}
+	res.Details, err = tx.GetFilmDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	if res.Details == nil {
+		res.Details = &models.FilmDetails{}
+	}
+	res.Releases, err = tx.GetFilmReleases(id)
+	if err != nil {
+		return nil, err
+	}
+	res.ExternalIDs, err = tx.GetFilmExternalIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	res.PosthumousActors, err = tx.GetFilmPosthumousActors(id)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func loadActorSnapshot(tx *db.DBProvider, id int) (*models.ActorSnapshot, error) {
+	actor, err := tx.GetActor(id)
+	if err != nil || actor.ID == 0 {
+		return nil, err
+	}
+	res := models.ActorSnapshot{Actor: *actorSnapshot(actor)}
+	res.Details, err = tx.GetActorDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	if res.Details == nil {
+		res.Details = &models.ActorDetails{}
+	}
+	res.ExternalIDs, err = tx.GetActorExternalIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Server) revisionsHandler(entity models.RevisionEntity, id int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	if len(segments) == 1 && segments[0] == "diff" && r.Method == http.MethodGet {
+		s.getRevisionsDiff(entity, id, w, r)
+		return
+	}
+	revisionID, err := utils.ParseSegmentID(segments, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid revision id"))
+		return
+	}
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		s.getRevisions(entity, id, w, r)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		s.getRevision(entity, id, revisionID, w, r)
+	case len(segments) == 2 && segments[1] == "revert" && r.Method == http.MethodPost:
+		s.revertRevision(entity, id, revisionID, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get film revisions
+// @Tags film
+// @Description История изменений фильма и его актерского состава: автор, время и состояние до и после изменения. История актера доступна по /actor/{id}/revisions
+// @ID get-film-revisions
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetRevisions
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/revisions [get]
+func (*Server) getRevisions(entity models.RevisionEntity, id int, w http.ResponseWriter, r *http.Request) {
+	get := db.Instance().GetFilmRevisions
+	if entity == constants.RevisionEntityActor {
+		get = db.Instance().GetActorRevisions
+	}
+	revisions, err := get(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get revisions from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"revisions": revisions})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Get film revision
+// @Tags film
+// @Description Одна запись из истории изменений фильма
+// @ID get-film-revision
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Param revision_id path int true "id записи истории"
+// @Success 200 {object} models.Revision
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/revisions/{revision_id} [get]
+func (*Server) getRevision(entity models.RevisionEntity, id int, revisionID int, w http.ResponseWriter, r *http.Request) {
+	revision, ok := entityRevision(entity, id, revisionID, w, r)
+	if !ok {
+		return
+	}
+	res, err := json.Marshal(revision)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Diff film revisions
+// @Tags film
+// @Description Различия в полях фильма между состояниями после двух изменений из истории
+// @ID get-film-revisions-diff
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Param from query int true "id более ранней записи истории"
+// @Param to query int true "id более поздней записи истории"
+// @Success 200 {object} models.RevisionDiff
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/revisions/diff [get]
+func (*Server) getRevisionsDiff(entity models.RevisionEntity, id int, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fromID, err := strconv.Atoi(query.Get("from"))
+	if err != nil || fromID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid from"))
+		return
+	}
+	toID, err := strconv.Atoi(query.Get("to"))
+	if err != nil || toID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid to"))
+		return
+	}
+	from, ok := entityRevision(entity, id, fromID, w, r)
+	if !ok {
+		return
+	}
+	to, ok := entityRevision(entity, id, toID, w, r)
+	if !ok {
+		return
+	}
+	changes, err := diffSnapshots(from.State(), to.State())
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot diff revisions: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(models.RevisionDiff{From: fromID, To: toID, Changes: changes})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Revert film
+// @Tags film
+// @Description Возврат полей фильма, подробностей, релизов, внешних id и отметок о посмертном участии к состоянию после указанного изменения из истории. Актерский состав не меняется. Сам возврат также попадает в историю
+// @ID revert-film
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Param revision_id path int true "id записи истории"
+// @Success 200 {string} string "film reverted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/revisions/{revision_id}/revert [post]
+func (*Server) revertRevision(entity models.RevisionEntity, id int, revisionID int, w http.ResponseWriter, r *http.Request) {
+	revision, ok := entityRevision(entity, id, revisionID, w, r)
+	if !ok {
+		return
+	}
+	err := db.Instance().InTx(func(tx *db.DBProvider) error {
+		var before, after any
+		var err error
+		if entity == constants.RevisionEntityFilm {
+			before, after, err = revertFilm(tx, id, revision.State())
+		} else {
+			before, after, err = revertActor(tx, id, revision.State())
+		}
+		if err != nil {
+			return fmt.Errorf("cannot revert %v: %w", entity, err)
+		}
+		if after == nil {
+			return db.ErrNotFound
+		}
+		return recordRevision(tx, r, entity, id, constants.RevisionRevert, before, after)
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("external id of the revision is used by another record"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(string(entity) + " reverted"))
+}
+
+// revertFilm applies the snapshot to the film and returns the film before and
+// after, or nil if the film does not exist. Parts of the film missing from
+// older snapshots are left as they are.
+func revertFilm(tx *db.DBProvider, id int, state json.RawMessage) (any, any, error) {
+	before, err := loadFilmSnapshot(tx, id)
+	if err != nil || before == nil {
+		return nil, nil, err
+	}
+	reverted := models.FilmSnapshot{}
+	err = json.Unmarshal(state, &reverted)
+	if err != nil {
+		return nil, nil, err
+	}
+	reverted.ID = id
+	err = tx.UpdateFilm(&reverted.Film)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reverted.Details != nil {
+		err = tx.SetFilmDetails(id, reverted.Details)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if reverted.Releases != nil {
+		err = tx.SetFilmReleases(id, reverted.Releases)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if reverted.ExternalIDs != nil {
+		err = tx.SetFilmExternalIDs(id, reverted.ExternalIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if reverted.PosthumousActors != nil {
+		err = tx.SetFilmPosthumousActors(id, reverted.PosthumousActors)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	after, err := loadFilmSnapshot(tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+func revertActor(tx *db.DBProvider, id int, state json.RawMessage) (any, any, error) {
+	before, err := loadActorSnapshot(tx, id)
+	if err != nil || before == nil {
+		return nil, nil, err
+	}
+	reverted := models.ActorSnapshot{}
+	err = json.Unmarshal(state, &reverted)
+	if err != nil {
+		return nil, nil, err
+	}
+	reverted.ID = id
+	err = tx.UpdateActor(&reverted.Actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reverted.Details != nil {
+		err = tx.SetActorDetails(id, reverted.Details)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if reverted.ExternalIDs != nil {
+		err = tx.SetActorExternalIDs(id, reverted.ExternalIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	after, err := loadActorSnapshot(tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// entityRevision gets the revision of the film or the actor itself, cast
+// revisions are not accepted. On failure the error is written to the response
+// and false is returned.
+func entityRevision(entity models.RevisionEntity, id int, revisionID int, w http.ResponseWriter, r *http.Request) (*models.Revision, bool) {
+	revision, err := db.Instance().GetRevision(revisionID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get revision from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return nil, false
+	}
+	if revision == nil || revision.Entity != entity || revision.EntityID != id {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("revision not found"))
+		return nil, false
+	}
+	return revision, true
+}
+
+// diffSnapshots compares top level fields of two snapshots, the id is never
+// reported.
+func diffSnapshots(from json.RawMessage, to json.RawMessage) ([]*models.FieldChange, error) {
+	fromFields, toFields := map[string]any{}, map[string]any{}
+	err := json.Unmarshal(from, &fromFields)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(to, &toFields)
+	if err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for field := range fromFields {
+		fields = append(fields, field)
+	}
+	for field := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	changes := []*models.FieldChange{}
+	for _, field := range fields {
+		if field == "id" || reflect.DeepEqual(fromFields[field], toFields[field]) {
+			continue
+		}
+		changes = append(changes, &models.FieldChange{Field: field, From: fromFields[field], To: toFields[field]})
+	}
+	return changes, nil
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// RoleRules declares permissions required by /role/ routes.
+var RoleRules = []middleware.Rule{
+	{Path: "/role/**", Permission: constants.PermissionRolesManage},
+}
+
+func (s *Server) RoleHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		s.getRoles(w, r)
+	case len(segments) == 1 && r.Method == http.MethodPost:
+		s.postRole(w, r)
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		s.getRole(segments[1], w, r)
+	case len(segments) == 2 && r.Method == http.MethodPut:
+		s.putRole(segments[1], w, r)
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		s.deleteRole(segments[1], w, r)
+	case len(segments) == 4 && segments[2] == "users" && r.Method == http.MethodPut:
+		s.assignRole(segments[1], segments[3], w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get roles
+// @Tags role
+// @Description Список ролей с их правами и список всех прав, которые можно выдать роли. Требуется право roles:manage
+// @ID get-roles
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.GetRoles
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/ [get]
+func (*Server) getRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := db.Instance().GetRoles()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get roles from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeRoleJSON(models.GetRoles{Roles: roles, Permissions: auth.Permissions}, http.StatusOK, w, r)
+}
+
+// @Summary Get role
+// @Tags role
+// @Description Роль и ее права. Требуется право roles:manage
+// @ID get-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "название роли"
+// @Success 200 {object} models.Role
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/{name} [get]
+func (*Server) getRole(name string, w http.ResponseWriter, r *http.Request) {
+	role, err := db.Instance().GetRole(name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get role from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if role == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	writeRoleJSON(role, http.StatusOK, w, r)
+}
+
+// @Summary Add role
+// @Tags role
+// @Description Создание роли с набором прав. Требуется право roles:manage
+// @ID add-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param requestBody body models.Role true "Название, описание и права роли"
+// @Success 201 {object} models.Role
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 409 {string} string "role already exists"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/ [post]
+func (*Server) postRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := readRole(w, r)
+	if !ok {
+		return
+	}
+	if !roleName.MatchString(role.Name) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("name should consist of lowercase latin letters, digits, '_' and '-' and be no more than 50 characters"))
+		return
+	}
+	if role.Description == nil {
+		description := ""
+		role.Description = &description
+	}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	if msg := validateRole(role); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	err := db.Instance().AddRole(role)
+	if db.IsUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("role already exists"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add role to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidatePermissions()
+	writeRoleJSON(role, http.StatusCreated, w, r)
+}
+
+// @Summary Update role
+// @Tags role
+// @Description Изменение описания и прав роли. Переданный список прав заменяет текущий. Роль admin изменить нельзя. Требуется право roles:manage
+// @ID update-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "название роли"
+// @Param requestBody body models.Role true "Описание и права роли, название игнорируется"
+// @Success 200 {object} models.Role
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/{name} [put]
+func (*Server) putRole(name string, w http.ResponseWriter, r *http.Request) {
+	if name == constants.AdminRole {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("admin role can not be changed"))
+		return
+	}
+	rolePut, ok := readRole(w, r)
+	if !ok {
+		return
+	}
+	if msg := validateRole(rolePut); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	oldRole, err := db.Instance().GetRole(name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get role from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if oldRole == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	role := oldRole.CopyWith(rolePut)
+	err = db.Instance().UpdateRole(role)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update role: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidatePermissions()
+	writeRoleJSON(role, http.StatusOK, w, r)
+}
+
+// @Summary Delete role
+// @Tags role
+// @Description Удаление роли. Роль, назначенную пользователям, и встроенные роли admin и viewer удалить нельзя. Требуется право roles:manage
+// @ID delete-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param name path string true "название роли"
+// @Success 200 {string} string "role deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 409 {string} string "role is assigned to users"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/{name} [delete]
+func (*Server) deleteRole(name string, w http.ResponseWriter, r *http.Request) {
+	if name == constants.AdminRole || name == constants.ViewerRole {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("%v role can not be deleted", name)))
+		return
+	}
+	n, err := db.Instance().DeleteRole(name)
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("role is assigned to users"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete role: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	auth.InvalidatePermissions()
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("role deleted"))
+}
+
+// @Summary Assign role
+// @Tags role
+// @Description Назначение роли пользователю. Свою роль изменить нельзя, чтобы не лишиться доступа. Refresh токены и сессии пользователя отзываются, выданные ранее access токены перестают приниматься, новая роль действует после повторного входа. Требуется право roles:manage
+// @ID assign-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param name path string true "название роли"
+// @Param login path string true "логин пользователя"
+// @Success 200 {string} string "role assigned"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /role/{name}/users/{login} [put]
+func (*Server) assignRole(name string, login string, w http.ResponseWriter, r *http.Request) {
+	if login == middleware.CurrentUser(r).Name {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot change own role"))
+		return
+	}
+	role, err := db.Instance().GetRole(name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get role from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if role == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("role not found"))
+		return
+	}
+	user, err := db.Instance().GetUser(login)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("user not found"))
+		return
+	}
+	err = db.Instance().SetUserRole(user.ID, role.Name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update user role: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("role assigned"))
+}
+
+func readRole(w http.ResponseWriter, r *http.Request) (*models.Role, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return nil, false
+	}
+	role := models.Role{}
+	err = json.Unmarshal(body, &role)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return nil, false
+	}
+	return &role, true
+}
+
+// validateRole checks the description and the permissions of the role and
+// removes duplicate permissions.
+func validateRole(role *models.Role) string {
+	if role.Description != nil && len(*role.Description) > 200 {
+		return "description len should not exceed 200 symbols"
+	}
+	if role.Permissions == nil {
+		return ""
+	}
+	known := map[string]bool{}
+	for _, permission := range auth.Permissions {
+		known[permission] = true
+	}
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, permission := range role.Permissions {
+		permission = strings.TrimSpace(permission)
+		if !known[permission] {
+			return fmt.Sprintf("unknown permission %v", permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	role.Permissions = permissions
+	return ""
+}
+
+func writeRoleJSON(respond any, status int, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(res)
+}
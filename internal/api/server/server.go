@@ -2,21 +2,34 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
 	"github.com/ffdb42/vk_trainee_task/internal/constants"
 	"github.com/ffdb42/vk_trainee_task/internal/db"
 	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/notify"
+	"github.com/ffdb42/vk_trainee_task/internal/storage"
 	"github.com/ffdb42/vk_trainee_task/internal/utils"
 	_ "github.com/swaggo/files"
 	_ "github.com/swaggo/http-swagger"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type Server struct{}
+type Server struct {
+	Store    storage.BlobStore
+	Notifier notify.Notifier
+	// InsecureCookies lets session cookies be sent over plain HTTP, it is
+	// meant for local development only.
+	InsecureCookies bool
+}
 
 // @Summary Sign up
 // @Tags auth
@@ -69,19 +82,19 @@ func (s *Server) SignUp(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("password was not provided"))
 		return
 	}
-	if len(pass) == 0 || len(pass) > 100 {
+	if err := auth.ValidatePassword(pass); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("pass length should be at least 1 and no more than 100 characters"))
+		w.Write([]byte(err.Error()))
 		return
 	}
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	hashedPass, err := auth.HashPassword(pass)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot generate hash for pass: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
 	}
-	user := models.User{Name: name, Password: string(hashedPass), Role: constants.UserRole}
+	user := models.User{Name: name, Password: hashedPass, Role: constants.ViewerRole}
 	err = db.Instance().AddUser(&user)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot add user to db: %v", r.Method, r.RequestURI, err)
@@ -93,10 +106,55 @@ func (s *Server) SignUp(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("user signed up"))
 }
 
+// ActorRules declares permissions required by /actor/ routes.
+var ActorRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/actor/**", Permission: constants.PermissionActorsRead},
+	{Method: http.MethodPost, Path: "/actor/*/revisions/*/revert", Permission: constants.PermissionRevisionsRevert},
+	{Method: http.MethodPost, Path: "/actor/*/merge", Permission: constants.PermissionActorsMerge},
+	{Method: http.MethodPost, Path: "/actor", Permission: constants.PermissionActorsCreate},
+	{Method: http.MethodDelete, Path: "/actor/*", Permission: constants.PermissionActorsDelete},
+	{Path: "/actor/**", Permission: constants.PermissionActorsUpdate},
+}
+
 func (s *Server) ActorHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) > 1 && segments[1] == "by-external" {
+		s.getActorByExternalID(segments[2:], w, r)
+		return
+	}
+	if len(segments) > 2 {
+		id, err := utils.ParseSegmentID(segments, 1)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		if !actorExists(id, w, r) {
+			return
+		}
+		switch segments[2] {
+		case "awards":
+			s.getActorAwards(id, w, r)
+		case "photo":
+			s.imageHandler(constants.ImageEntityActor, id, w, r)
+		case "translations":
+			s.actorTranslationsHandler(id, segments[3:], w, r)
+		case "aliases":
+			s.actorAliasesHandler(id, segments[3:], w, r)
+		case "merge":
+			s.mergeActors(id, w, r)
+		case "revisions":
+			s.revisionsHandler(constants.RevisionEntityActor, id, segments[3:], w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		id, err := utils.ParseID(r.RequestURI)
+		id, err := utils.ParseSegmentID(segments, 1)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			w.Write([]byte(err.Error()))
@@ -121,10 +179,57 @@ func (s *Server) ActorHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// FilmRules declares permissions required by /film/ routes.
+var FilmRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/film/**", Permission: constants.PermissionFilmsRead},
+	{Method: http.MethodPost, Path: "/film/*/revisions/*/revert", Permission: constants.PermissionRevisionsRevert},
+	{Method: http.MethodPost, Path: "/film", Permission: constants.PermissionFilmsCreate},
+	{Method: http.MethodDelete, Path: "/film/*", Permission: constants.PermissionFilmsDelete},
+	{Path: "/film/**", Permission: constants.PermissionFilmsUpdate},
+}
+
 func (s *Server) FilmHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) > 1 && segments[1] == "by-external" {
+		s.getFilmByExternalID(segments[2:], w, r)
+		return
+	}
+	if len(segments) > 2 {
+		id, err := utils.ParseSegmentID(segments, 1)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		if !filmExists(id, w, r) {
+			return
+		}
+		switch segments[2] {
+		case "relations":
+			s.filmRelationsHandler(id, segments[3:], w, r)
+		case "awards":
+			s.getFilmAwards(id, w, r)
+		case "poster":
+			s.imageHandler(constants.ImageEntityFilm, id, w, r)
+		case "translations":
+			s.filmTranslationsHandler(id, segments[3:], w, r)
+		case "revisions":
+			s.revisionsHandler(constants.RevisionEntityFilm, id, segments[3:], w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		id, _ := utils.ParseID(r.RequestURI)
+		id, err := utils.ParseSegmentID(segments, 1)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
 
 		if id > 0 {
 			s.getFilm(id, w, r)
@@ -144,12 +249,21 @@ func (s *Server) FilmHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SearchRules declares permissions required by /search/ routes.
+var SearchRules = []middleware.Rule{
+	{Method: http.MethodGet, Path: "/search/**", Permission: constants.PermissionFilmsRead},
+}
+
 // @Summary Search
 // @Tags search
-// @Description Поиск фильмов по фрагменту из названия или фрагменту имени актера, который указан в титрах
+// @Description Поиск фильмов по фрагменту из названия или фрагменту имени актера, который указан в титрах. Поиск ведется по всем переводам
 // @ID search
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
+// @Param lang query string false "язык ответа, приоритетнее заголовка Accept-Language"
+// @Param Accept-Language header string false "предпочитаемые языки"
 // @Param search_by query string true "искомый фрагмент"
 // @Success 200 {object} models.FilmsSearch
 // @Failure 400 {string} string "error string"
@@ -176,6 +290,13 @@ func (s *Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("internal server error"))
 		return
 	}
+	err = newLocalizer(w, r).films(films...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
 	res, err := json.Marshal(map[string]any{"films": films})
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot marshal to json: %v", r.Method, r.RequestURI, err)
@@ -188,18 +309,32 @@ func (s *Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Get actor
 // @Tags actor
-// @Description Поиск актера по id
+// @Description Поиск актера по id. Возраст актера (или возраст на момент смерти) и возраст на момент выхода каждого фильма вычисляются автоматически
 // @ID get-actor
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
+// @Param lang query string false "язык ответа, приоритетнее заголовка Accept-Language"
+// @Param Accept-Language header string false "предпочитаемые языки"
 // @Param id path int true "id"
 // @Success 200 {object} models.ActorRespond
+// @Param as_of query string false "момент времени в формате dd.mm.yyyy или ISO 8601, на который нужно восстановить состояние. Состояние восстанавливается по журналу ревизий, записи без изменений после этого момента возвращаются в текущем виде"
+// @Success 301 {string} string "актер был объединен с другим"
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /actor/{id} [get]
-func (*Server) getActor(id int, w http.ResponseWriter, r *http.Request) {
+func (s *Server) getActor(id int, w http.ResponseWriter, r *http.Request) {
+	asOf, err := parseAsOf(r)
+	if writeAsOfError(err, w) {
+		return
+	}
+	if asOf != nil {
+		s.getActorAsOf(id, *asOf, w, r)
+		return
+	}
 	actor, err := db.Instance().GetActor(id)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
@@ -207,7 +342,24 @@ func (*Server) getActor(id int, w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("cannot get actor"))
 		return
 	}
-	respond := models.ActorRespond{Actor: actor, Films: []*models.Film{}}
+	if actor.ID == 0 {
+		newID, err := db.Instance().GetActorRedirect(id)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get redirect from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("cannot get actor"))
+			return
+		}
+		if newID > 0 {
+			location := url.URL{Path: fmt.Sprintf("/actor/%v", newID), RawQuery: r.URL.RawQuery}
+			http.Redirect(w, r, location.String(), http.StatusMovedPermanently)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	respond := models.ActorRespond{Actor: actor, Films: []*models.ActorFilm{}}
 	films, err := db.Instance().GetActorFilms(id)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot get films list from db: %v", r.Method, r.RequestURI, err)
@@ -216,6 +368,35 @@ func (*Server) getActor(id int, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	respond.Films = films
+	actor.Details, err = db.Instance().GetActorDetails(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actor details from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	setActorAges(actor, films)
+	actor.ExternalIDs, err = db.Instance().GetActorExternalIDs(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	respond.Photo, err = s.imageURLs(constants.ImageEntityActor, id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get photo from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
+	err = newLocalizer(w, r).actorResponds(&respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actor"))
+		return
+	}
 	res, err := json.Marshal(respond)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
@@ -231,14 +412,27 @@ func (*Server) getActor(id int, w http.ResponseWriter, r *http.Request) {
 // @Description Получения списка актеров
 // @ID get-actors
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
+// @Param lang query string false "язык ответа, приоритетнее заголовка Accept-Language"
+// @Param Accept-Language header string false "предпочитаемые языки"
+// @Param as_of query string false "момент времени в формате dd.mm.yyyy или ISO 8601, на который нужно восстановить состояние. Состояние восстанавливается по журналу ревизий, записи без изменений после этого момента возвращаются в текущем виде"
 // @Success 200 {object} models.GetActors
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /actor/ [get]
-func (*Server) getActors(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getActors(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parseAsOf(r)
+	if writeAsOfError(err, w) {
+		return
+	}
+	if asOf != nil {
+		s.getActorsAsOf(*asOf, w, r)
+		return
+	}
 	actors, err := db.Instance().GetActors()
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
@@ -255,7 +449,33 @@ func (*Server) getActors(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("cannot get actor"))
 			return
 		}
-		respond = append(respond, models.ActorRespond{Actor: &v, Films: films})
+		v.Details, err = db.Instance().GetActorDetails(v.ID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get actor details from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("cannot get actor"))
+			return
+		}
+		setActorAges(&v, films)
+		photo, err := s.imageURLs(constants.ImageEntityActor, v.ID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get photo from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("cannot get actor"))
+			return
+		}
+		respond = append(respond, models.ActorRespond{Actor: &v, Films: films, Photo: photo})
+	}
+	responds := []*models.ActorRespond{}
+	for i := range respond {
+		responds = append(responds, &respond[i])
+	}
+	err = newLocalizer(w, r).actorResponds(responds...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get actors"))
+		return
 	}
 	res, err := json.Marshal(map[string]any{"actors": respond})
 	if err != nil {
@@ -269,16 +489,20 @@ func (*Server) getActors(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Add actor
 // @Tags actor
-// @Description Создание записи об актере
+// @Description Создание записи об актере. Дата смерти должна быть позже даты рождения. Если найдены вероятные дубликаты (по имени с учетом переводов и псевдонимов и по дате рождения), возвращается 409 со списком совпадений; создать запись все равно можно с force=true
 // @ID post-actor
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
+// @Param force query bool false "создать актера, даже если найдены вероятные дубликаты"
 // @Param requestBody body models.ActorPost true "Информация об актере"
 // @Success 200 {string} string "actor added"
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 403 {string} string "forbidden"
+// @Failure 409 {object} models.GetDuplicates
 // @Failure 500 {string} string "internal server error"
 // @Router /actor/ [post]
 func (*Server) postActor(w http.ResponseWriter, r *http.Request) {
@@ -312,9 +536,71 @@ func (*Server) postActor(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("sex should be 'm' or 'f'"))
 		return
 	}
-	err = db.Instance().AddActor(&actor)
+	if msg := validateActorDetails(actor.Details); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	if actor.Details != nil {
+		if msg := validateDeathDate(actor.Birthdate, actor.Details.DeathDate, nil); msg != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(msg))
+			return
+		}
+	}
+	if msg := validateExternalIDs(actor.ExternalIDs, actorExternalIDFormats); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	msg, err := checkExternalIDsOwner(actor.ExternalIDs, 0, db.Instance().GetActorByExternalID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	if !isForced(r) {
+		matches, err := findActorDuplicates(&actor)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot check duplicates: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
+			return
+		}
+		if len(matches) > 0 {
+			writeDuplicates(matches, w, r)
+			return
+		}
+	}
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		actorID, err := tx.AddActor(&actor)
+		if err != nil {
+			return fmt.Errorf("cannot add value to db: %w", err)
+		}
+		err = saveActorDetails(tx, actorID, actor.Details)
+		if err != nil {
+			return fmt.Errorf("cannot add actor details: %w", err)
+		}
+		if actor.ExternalIDs != nil {
+			err = tx.SetActorExternalIDs(actorID, actor.ExternalIDs)
+			if err != nil {
+				return fmt.Errorf("cannot add external ids: %w", err)
+			}
+		}
+		created, err := loadActorSnapshot(tx, actorID)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		return recordRevision(tx, r, constants.RevisionEntityActor, actorID, constants.RevisionCreate, nil, created)
+	})
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
@@ -325,9 +611,11 @@ func (*Server) postActor(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Update actor
 // @Tags actor
-// @Description Изменение записи об актере
+// @Description Изменение записи об актере. Дата смерти должна быть позже даты рождения и не раньше выхода фильмов с участием актера, если участие в них не отмечено как посмертное
 // @ID put-actor
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Param id path int true "id"
 // @Param requestBody body models.ActorPost true "Информация об актере"
@@ -335,10 +623,11 @@ func (*Server) postActor(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /actor/{id} [put]
 func (*Server) putActor(w http.ResponseWriter, r *http.Request) {
-	id, err := utils.ParseID(r.RequestURI)
+	id, err := utils.ParseID(r.URL.Path)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -356,6 +645,11 @@ func (*Server) putActor(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("internal server error"))
 		return
 	}
+	if oldActor.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
@@ -386,10 +680,81 @@ func (*Server) putActor(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("sex should be 'm' or 'f'"))
 		return
 	}
+	if msg := validateActorDetails(actor.Details); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	if msg := validateExternalIDs(actor.ExternalIDs, actorExternalIDFormats); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	msg, err := checkExternalIDsOwner(actor.ExternalIDs, id, db.Instance().GetActorByExternalID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
 	updatedActor := oldActor.CopyWith(&actor)
-	err = db.Instance().UpdateActor(updatedActor)
+	details, err := db.Instance().GetActorDetails(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actor details from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if details == nil {
+		details = &models.ActorDetails{}
+	}
+	if actor.Details != nil {
+		details = details.CopyWith(actor.Details)
+	}
+	films, err := db.Instance().GetActorFilms(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get films list from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg := validateDeathDate(updatedActor.Birthdate, details.DeathDate, films); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		before, err := loadActorSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		err = tx.UpdateActor(updatedActor)
+		if err != nil {
+			return fmt.Errorf("cannot update actor: %w", err)
+		}
+		err = saveActorDetails(tx, id, actor.Details)
+		if err != nil {
+			return fmt.Errorf("cannot update actor details: %w", err)
+		}
+		if actor.ExternalIDs != nil {
+			err = tx.SetActorExternalIDs(id, actor.ExternalIDs)
+			if err != nil {
+				return fmt.Errorf("cannot update external ids: %w", err)
+			}
+		}
+		after, err := loadActorSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		return recordRevision(tx, r, constants.RevisionEntityActor, id, constants.RevisionUpdate, before, after)
+	})
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot update actor: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
@@ -400,9 +765,11 @@ func (*Server) putActor(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Delete actor
 // @Tags actor
-// @Description Удаление актера по id
+// @Description Перемещение актера в корзину. Актера вместе с его ролями можно восстановить через /trash до истечения срока хранения
 // @ID delete-actor
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "id"
 // @Success 200 {string} string "actor deleted"
 // @Failure 400 {string} string "error string"
@@ -410,8 +777,8 @@ func (*Server) putActor(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /actor/{id} [delete]
-func (*Server) deleteActor(w http.ResponseWriter, r *http.Request) {
-	id, err := utils.ParseID(r.RequestURI)
+func (s *Server) deleteActor(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r.URL.Path)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -422,9 +789,23 @@ func (*Server) deleteActor(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("invalid id"))
 		return
 	}
-	n, err := db.Instance().DeleteActor(id)
+	var n int64
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		actor, err := loadActorSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		n, err = tx.DeleteActor(id, middleware.CurrentUser(r).Name)
+		if err != nil {
+			return fmt.Errorf("cannot delete value from db: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+		return recordRevision(tx, r, constants.RevisionEntityActor, id, constants.RevisionDelete, actor, nil)
+	})
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
@@ -443,20 +824,38 @@ func (*Server) deleteActor(w http.ResponseWriter, r *http.Request) {
 // @Description Поиск фильма по id
 // @ID get-film
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
+// @Param lang query string false "язык ответа, приоритетнее заголовка Accept-Language"
+// @Param Accept-Language header string false "предпочитаемые языки"
 // @Param id path int true "id"
+// @Param as_of query string false "момент времени в формате dd.mm.yyyy или ISO 8601, на который нужно восстановить состояние. Состояние восстанавливается по журналу ревизий, записи без изменений после этого момента возвращаются в текущем виде"
 // @Success 200 {object} models.FilmRespond
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /film/{id} [get]
-func (*Server) getFilm(id int, w http.ResponseWriter, r *http.Request) {
+func (s *Server) getFilm(id int, w http.ResponseWriter, r *http.Request) {
+	asOf, err := parseAsOf(r)
+	if writeAsOfError(err, w) {
+		return
+	}
+	if asOf != nil {
+		s.getFilmAsOf(id, *asOf, w, r)
+		return
+	}
 	film, err := db.Instance().GetFilm(id)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("cannot get actor"))
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	if film.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
 		return
 	}
 	respond := models.FilmRespond{Film: film, Actors: []*models.Actor{}}
@@ -468,6 +867,57 @@ func (*Server) getFilm(id int, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	respond.Actors = actors
+	franchises, err := db.Instance().GetFilmFranchises(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get franchises list from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond.Franchises = franchises
+	relations, err := db.Instance().GetFilmRelations(id, "", "")
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get relations list from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond.Relations = relations
+	respond.Poster, err = s.imageURLs(constants.ImageEntityFilm, id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get poster from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond.Details, err = db.Instance().GetFilmDetails(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get film details from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond.Releases, err = db.Instance().GetFilmReleases(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get film releases from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	respond.ExternalIDs, err = db.Instance().GetFilmExternalIDs(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
+	err = newLocalizer(w, r).filmResponds(&respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get film"))
+		return
+	}
 	res, err := json.Marshal(respond)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
@@ -483,14 +933,25 @@ func (*Server) getFilm(id int, w http.ResponseWriter, r *http.Request) {
 // @Description Получения списка фильмов
 // @ID get-films
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
+// @Param lang query string false "язык ответа, приоритетнее заголовка Accept-Language"
+// @Param Accept-Language header string false "предпочитаемые языки"
+// @Param sort_by query string false "name, rating, release_date или box_office. Кассовые сборы сравниваются без учета валюты"
+// @Param sort_order query string false "ASC или DESC"
+// @Param award_won query int false "id награды, которую получил фильм"
+// @Param runtime_min query int false "минимальная продолжительность в минутах"
+// @Param runtime_max query int false "максимальная продолжительность в минутах"
+// @Param released_in query string false "код страны, в которой фильм вышел в прокат, например RU"
+// @Param as_of query string false "момент времени в формате dd.mm.yyyy или ISO 8601, на который нужно восстановить состояние. Состояние восстанавливается по журналу ревизий, записи без изменений после этого момента возвращаются в текущем виде. Фильтры вместе с as_of не поддерживаются"
 // @Success 200 {object} models.GetFilms
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /film/ [get]
-func (*Server) getFilms(w http.ResponseWriter, r *http.Request) {
+func (s *Server) getFilms(w http.ResponseWriter, r *http.Request) {
 	var sortBy models.SortBy
 	var sortOrder models.SortOrder
 	query := r.URL.Query()
@@ -503,6 +964,8 @@ func (*Server) getFilms(w http.ResponseWriter, r *http.Request) {
 			sortBy = constants.SortByName
 		case "release_date":
 			sortBy = constants.SortByReleaseDate
+		case "box_office":
+			sortBy = constants.SortByBoxOffice
 		case "rating":
 			fallthrough
 		default:
@@ -523,7 +986,50 @@ func (*Server) getFilms(w http.ResponseWriter, r *http.Request) {
 			sortOrder = constants.SortDesc
 		}
 	}
-	films, err := db.Instance().GetFilms(sortBy, sortOrder)
+	asOf, err := parseAsOf(r)
+	if writeAsOfError(err, w) {
+		return
+	}
+	if asOf != nil {
+		if query.Has("award_won") || query.Has("runtime_min") || query.Has("runtime_max") || query.Has("released_in") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("filters cannot be combined with as_of"))
+			return
+		}
+		s.getFilmsAsOf(*asOf, sortBy, sortOrder, w, r)
+		return
+	}
+	filter := models.FilmsFilter{}
+	if query.Has("award_won") {
+		awardID, err := strconv.Atoi(query.Get("award_won"))
+		if err != nil || awardID < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid award_won"))
+			return
+		}
+		filter.AwardWonID = awardID
+	}
+	for param, value := range map[string]*int{"runtime_min": &filter.RuntimeMin, "runtime_max": &filter.RuntimeMax} {
+		if !query.Has(param) {
+			continue
+		}
+		runtime, err := strconv.Atoi(query.Get(param))
+		if err != nil || runtime < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("invalid %v", param)))
+			return
+		}
+		*value = runtime
+	}
+	if query.Has("released_in") {
+		filter.ReleasedIn = strings.ToUpper(query.Get("released_in"))
+		if !countryCode.MatchString(filter.ReleasedIn) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("released_in should be an ISO 3166-1 alpha-2 code"))
+			return
+		}
+	}
+	films, err := db.Instance().GetFilms(sortBy, sortOrder, &filter)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -539,7 +1045,32 @@ func (*Server) getFilms(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("cannot get film"))
 			return
 		}
-		respond = append(respond, models.FilmRespond{Film: &v, Actors: actors})
+		poster, err := s.imageURLs(constants.ImageEntityFilm, v.ID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get poster from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("cannot get film"))
+			return
+		}
+		details, err := db.Instance().GetFilmDetails(v.ID)
+		if err != nil {
+			log.Printf("ERROR %v %v: cannot get film details from db: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("cannot get film"))
+			return
+		}
+		respond = append(respond, models.FilmRespond{Film: &v, Actors: actors, Poster: poster, Details: details})
+	}
+	responds := []*models.FilmRespond{}
+	for i := range respond {
+		responds = append(responds, &respond[i])
+	}
+	err = newLocalizer(w, r).filmResponds(responds...)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("cannot get films"))
+		return
 	}
 	res, err := json.Marshal(respond)
 	if err != nil {
@@ -553,16 +1084,20 @@ func (*Server) getFilms(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Add film
 // @Tags film
-// @Description Создание записи об фильме
+// @Description Создание записи об фильме. Актеры из posthumous_actors_ids отмечаются как снявшиеся посмертно, без этой отметки фильм не может выйти после смерти актера. Если найдены вероятные дубликаты (по названию с учетом переводов и по году выхода), возвращается 409 со списком совпадений; создать запись все равно можно с force=true
 // @ID post-film
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce json
+// @Param force query bool false "создать фильм, даже если найдены вероятные дубликаты"
 // @Param requestBody body models.FilmPostDoc true "Информация о фильме`"
 // @Success 200 {string} string "film added"
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 403 {string} string "forbidden"
+// @Failure 409 {object} models.GetDuplicates
 // @Failure 500 {string} string "internal server error"
 // @Router /film/ [post]
 func (*Server) postFilm(w http.ResponseWriter, r *http.Request) {
@@ -596,21 +1131,88 @@ func (*Server) postFilm(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("film's rating should be from 0 to 10"))
 		return
 	}
-	filmID, err := db.Instance().AddFilm(&filmPost.Film)
+	if msg := validateFilmExtras(&filmPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	msg, err := validateFilmCredits(filmPost.Film.ReleaseDate, filmPost.ActorsList, filmPost.PosthumousActors)
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: cannot get actors details from db: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
 	}
-	for _, actorID := range filmPost.ActorsList {
-		err := db.Instance().AddFilmsActors(actorID, filmID)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	msg, err = checkExternalIDsOwner(filmPost.ExternalIDs, 0, db.Instance().GetFilmByExternalID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	if !isForced(r) {
+		matches, err := findFilmDuplicates(&filmPost.Film)
 		if err != nil {
-			log.Printf("ERROR %v %v: cannot add FilmActor: %v", r.Method, r.RequestURI, err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf("cannot add actor with id %v", actorID)))
+			log.Printf("ERROR %v %v: cannot check duplicates: %v", r.Method, r.RequestURI, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal server error"))
 			return
 		}
+		if len(matches) > 0 {
+			writeDuplicates(matches, w, r)
+			return
+		}
+	}
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		filmID, err := tx.AddFilm(&filmPost.Film)
+		if err != nil {
+			return fmt.Errorf("cannot add value to db: %w", err)
+		}
+		err = saveFilmExtras(tx, filmID, &filmPost)
+		if err != nil {
+			return fmt.Errorf("cannot add film details: %w", err)
+		}
+		added, err := addCast(tx, filmID, filmPost.ActorsList)
+		if err != nil {
+			return err
+		}
+		if filmPost.PosthumousActors != nil {
+			err = tx.SetFilmPosthumousActors(filmID, filmPost.PosthumousActors)
+			if err != nil {
+				return fmt.Errorf("cannot flag posthumous actors: %w", err)
+			}
+		}
+		film, err := loadFilmSnapshot(tx, filmID)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		err = recordRevision(tx, r, constants.RevisionEntityFilm, filmID, constants.RevisionCreate, nil, film)
+		if err != nil {
+			return err
+		}
+		return recordCast(tx, r, filmID, added, nil)
+	})
+	var missingActor missingActorError
+	if errors.As(err, &missingActor) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(missingActor.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("film added"))
@@ -618,9 +1220,11 @@ func (*Server) postFilm(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Update film
 // @Tags film
-// @Description Изменение записи о фильме
+// @Description Изменение записи о фильме. Если передан posthumous_actors_ids, он заменяет список актеров, снявшихся посмертно
 // @ID put-film
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Param id path int true "id"
 // @Param requestBody body models.FilmPostDoc true "Информация о фильме"
@@ -628,10 +1232,11 @@ func (*Server) postFilm(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "error string"
 // @Failure 401 {string} string "unauthtorized"
 // @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /film/{id} [put]
 func (*Server) putFilm(w http.ResponseWriter, r *http.Request) {
-	id, err := utils.ParseID(r.RequestURI)
+	id, err := utils.ParseID(r.URL.Path)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -649,6 +1254,11 @@ func (*Server) putFilm(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("internal server error"))
 		return
 	}
+	if oldFilm.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
@@ -679,35 +1289,161 @@ func (*Server) putFilm(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("film's rating should be from 0 to 10"))
 		return
 	}
+	if msg := validateFilmExtras(&filmPost.FilmPost); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
 	updatedFilm := oldFilm.CopyWith(&filmPost.Film)
-	err = db.Instance().UpdateFilm(updatedFilm)
+	credited, posthumous, err := filmCredits(id, &filmPost)
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot update actor: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: cannot get film credits from db: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
 	}
-	for _, actorID := range filmPost.ActorsList {
-		err := db.Instance().AddFilmsActors(actorID, updatedFilm.ID)
+	msg, err := validateFilmCredits(updatedFilm.ReleaseDate, credited, posthumous)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get actors details from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	msg, err = checkExternalIDsOwner(filmPost.ExternalIDs, id, db.Instance().GetFilmByExternalID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get external ids from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		before, err := loadFilmSnapshot(tx, id)
 		if err != nil {
-			log.Printf("ERROR %v %v: cannot add FilmActor: %v", r.Method, r.RequestURI, err)
+			return fmt.Errorf("cannot get value from db: %w", err)
 		}
-	}
-	for _, actorID := range filmPost.RemoveActors {
-		err := db.Instance().DeleteFilmsActors(id, actorID)
+		err = tx.UpdateFilm(updatedFilm)
+		if err != nil {
+			return fmt.Errorf("cannot update film: %w", err)
+		}
+		err = saveFilmExtras(tx, id, &filmPost.FilmPost)
+		if err != nil {
+			return fmt.Errorf("cannot update film details: %w", err)
+		}
+		added, err := addCast(tx, id, filmPost.ActorsList)
+		if err != nil {
+			return err
+		}
+		removed := []int{}
+		for _, actorID := range filmPost.RemoveActors {
+			n, err := tx.DeleteFilmsActors(id, actorID)
+			if err != nil {
+				return fmt.Errorf("cannot delete FilmActor: %w", err)
+			}
+			if n > 0 {
+				removed = append(removed, actorID)
+			}
+		}
+		if filmPost.PosthumousActors != nil {
+			err = tx.SetFilmPosthumousActors(id, filmPost.PosthumousActors)
+			if err != nil {
+				return fmt.Errorf("cannot flag posthumous actors: %w", err)
+			}
+		}
+		after, err := loadFilmSnapshot(tx, id)
 		if err != nil {
-			log.Printf("ERROR %v %v: cannot delete FilmActor: %v", r.Method, r.RequestURI, err)
+			return fmt.Errorf("cannot get value from db: %w", err)
 		}
+		err = recordRevision(tx, r, constants.RevisionEntityFilm, id, constants.RevisionUpdate, before, after)
+		if err != nil {
+			return err
+		}
+		return recordCast(tx, r, id, added, removed)
+	})
+	var missingActor missingActorError
+	if errors.As(err, &missingActor) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(missingActor.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("film updated"))
 }
 
+// missingActorError rolls back a change that credits an actor that does not
+// exist or is in the trash.
+type missingActorError int
+
+func (e missingActorError) Error() string {
+	return fmt.Sprintf("cannot add actor with id %v", int(e))
+}
+
+// addCast credits the actors in the film and returns the ones that were not
+// credited before. Actors in the trash are rejected, since they would only
+// show up in the cast after a restore.
+func addCast(tx *db.DBProvider, filmID int, actorIDs []int) ([]int, error) {
+	res := []int{}
+	for _, actorID := range actorIDs {
+		exists, err := tx.ActorExists(actorID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get value from db: %w", err)
+		}
+		if !exists {
+			return nil, missingActorError(actorID)
+		}
+		added, err := tx.AddFilmsActors(actorID, filmID)
+		if db.IsForeignKeyViolation(err) {
+			return nil, missingActorError(actorID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot add FilmActor: %w", err)
+		}
+		if added {
+			res = append(res, actorID)
+		}
+	}
+	return res, nil
+}
+
+// recordCast records the cast links added to and removed from the film.
+func recordCast(tx *db.DBProvider, r *http.Request, filmID int, added []int, removed []int) error {
+	for _, actorID := range added {
+		err := recordRevision(tx, r, constants.RevisionEntityCast, filmID, constants.RevisionCreate, nil, castSnapshot(filmID, actorID))
+		if err != nil {
+			return err
+		}
+	}
+	for _, actorID := range removed {
+		err := recordRevision(tx, r, constants.RevisionEntityCast, filmID, constants.RevisionDelete, castSnapshot(filmID, actorID), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // @Summary Delete film
 // @Tags film
-// @Description Удаление фильма по id
+// @Description Перемещение фильма в корзину. Фильм вместе с актерским составом можно восстановить через /trash до истечения срока хранения
 // @ID delete-film
 // @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Param id path int true "id"
 // @Success 200 {string} string "film deleted"
 // @Failure 400 {string} string "error string"
@@ -715,8 +1451,8 @@ func (*Server) putFilm(w http.ResponseWriter, r *http.Request) {
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /film/{id} [delete]
-func (*Server) deleteFilm(w http.ResponseWriter, r *http.Request) {
-	id, err := utils.ParseID(r.RequestURI)
+func (s *Server) deleteFilm(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r.URL.Path)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
@@ -727,9 +1463,23 @@ func (*Server) deleteFilm(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("invalid id"))
 		return
 	}
-	n, err := db.Instance().DeleteFilm(id)
+	var n int64
+	err = db.Instance().InTx(func(tx *db.DBProvider) error {
+		film, err := loadFilmSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		n, err = tx.DeleteFilm(id, middleware.CurrentUser(r).Name)
+		if err != nil {
+			return fmt.Errorf("cannot delete value from db: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+		return recordRevision(tx, r, constants.RevisionEntityFilm, id, constants.RevisionDelete, film, nil)
+	})
 	if err != nil {
-		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("internal server error"))
 		return
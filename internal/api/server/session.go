@@ -0,0 +1,289 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// SessionHandler serves sessions of the current user.
+func (s *Server) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.getSessions(w, r)
+		case http.MethodDelete:
+			s.revokeOtherSessions(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Unexpected HTTP method"))
+		}
+		return
+	}
+	id, err := utils.ParseSegmentID(segments, 1)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	if len(segments) == 2 && r.Method == http.MethodDelete {
+		s.revokeSession(id, w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("not found"))
+}
+
+// @Summary Log in
+// @Tags auth
+// @Description Вход для браузерных клиентов. Создает сессию и устанавливает HttpOnly cookie session и cookie csrf_token. Запросы кроме GET, HEAD и OPTIONS с cookie сессии должны передавать значение csrf_token в заголовке X-CSRF-Token. Сессия завершается после 30 минут бездействия или через 12 часов после входа. Пользователь, которому требуется сменить пароль, получает сессию, с которой доступна только смена пароля
+// @ID login
+// @Accept json
+// @Produce json
+// @Param requestBody body models.LoginRequest true "Логин и пароль"
+// @Success 200 {object} models.LoginRespond
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 403 {string} string "account is disabled"
+// @Failure 429 {string} string "too many failed login attempts"
+// @Failure 500 {string} string "internal server error"
+// @Router /auth/login [post]
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	request := models.LoginRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	user, err := auth.Login(request.Name, request.Password, auth.ClientIP(r))
+	var lockedErr *auth.LockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", lockedErr.RetryAfterSeconds())
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(lockedErr.Error()))
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("unauthorized"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	err = auth.CheckAccount(user, time.Now())
+	resetRequired := errors.Is(err, auth.ErrPasswordResetRequired)
+	if errors.Is(err, auth.ErrAccountDisabled) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil && !resetRequired {
+		log.Printf("ERROR %v %v: cannot get users from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	session, token, csrfToken, err := auth.NewSession(user, r)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add session to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	s.setSessionCookies(w, token, csrfToken, session.ExpiresAt)
+	writeSessionJSON(models.LoginRespond{
+		CSRFToken:             csrfToken,
+		ExpiresAt:             session.ExpiresAt,
+		PasswordResetRequired: resetRequired,
+	}, w, r)
+}
+
+// @Summary Log out
+// @Tags auth
+// @Description Завершение текущей сессии и удаление ее cookie. Требуется заголовок X-CSRF-Token
+// @ID logout
+// @Produce plain
+// @Success 200 {string} string "logged out"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 403 {string} string "csrf token is missing or invalid"
+// @Failure 500 {string} string "internal server error"
+// @Router /auth/logout [post]
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unexpected method"))
+		return
+	}
+	session := middleware.CurrentSession(r)
+	if session == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("request was not made with a session"))
+		return
+	}
+	_, err := db.Instance().RevokeSession(session.ID, session.UserID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot revoke session: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	s.setSessionCookies(w, "", "", time.Time{})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("logged out"))
+}
+
+// @Summary Get sessions
+// @Tags auth
+// @Description Активные сессии текущего пользователя, сначала использованные последними. Сессия запроса отмечена current
+// @ID get-sessions
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.GetSessions
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /sessions/ [get]
+func (*Server) getSessions(w http.ResponseWriter, r *http.Request) {
+	writeSessions(middleware.CurrentUser(r).ID, w, r)
+}
+
+// @Summary Revoke session
+// @Tags auth
+// @Description Завершение сессии текущего пользователя по id
+// @ID revoke-session
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param id path int true "id"
+// @Success 200 {string} string "session revoked"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /sessions/{id} [delete]
+func (*Server) revokeSession(id int, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().RevokeSession(id, middleware.CurrentUser(r).ID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot revoke session: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("session revoked"))
+}
+
+// @Summary Revoke other sessions
+// @Tags auth
+// @Description Завершение всех сессий текущего пользователя, кроме сессии запроса
+// @ID revoke-other-sessions
+// @Security BasicAuth
+// @Security BearerAuth
+// @Success 200 {string} string "sessions revoked"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /sessions/ [delete]
+func (*Server) revokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	exceptID := 0
+	if session := middleware.CurrentSession(r); session != nil {
+		exceptID = session.ID
+	}
+	revokeSessions(middleware.CurrentUser(r).ID, exceptID, w, r)
+}
+
+func writeSessions(userID int, w http.ResponseWriter, r *http.Request) {
+	sessions, err := db.Instance().GetSessions(userID, time.Now().Add(-constants.SessionIdleTimeout))
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get sessions from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if current := middleware.CurrentSession(r); current != nil {
+		for _, session := range sessions {
+			session.Current = session.ID == current.ID
+		}
+	}
+	writeSessionJSON(models.GetSessions{Sessions: sessions}, w, r)
+}
+
+func revokeSessions(userID int, exceptID int, w http.ResponseWriter, r *http.Request) {
+	_, err := db.Instance().RevokeSessions(userID, exceptID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot revoke sessions: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("sessions revoked"))
+}
+
+// setSessionCookies sets the session and CSRF cookies, empty tokens remove
+// them. The CSRF cookie is readable by scripts of the front-end.
+func (s *Server) setSessionCookies(w http.ResponseWriter, token string, csrfToken string, expiresAt time.Time) {
+	maxAge := 0
+	if token == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   !s.InsecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   !s.InsecureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func writeSessionJSON(respond any, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
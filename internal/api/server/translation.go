@@ -0,0 +1,514 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// localizer replaces names and descriptions with their translations into the
+// first language of the fallback chain that has one.
+type localizer struct {
+	langs []string
+}
+
+// newLocalizer builds the fallback chain: ?lang=, then Accept-Language in
+// preference order, then base languages of regional tags, then
+// constants.DefaultLanguage. Records without any matching translation are
+// returned as stored.
+func newLocalizer(w http.ResponseWriter, r *http.Request) *localizer {
+	w.Header().Add("Vary", "Accept-Language")
+	requested := []string{}
+	if lang, ok := utils.NormalizeLanguage(r.URL.Query().Get("lang")); ok {
+		requested = append(requested, lang)
+	}
+	requested = append(requested, utils.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+
+	l := localizer{langs: []string{}}
+	seen := map[string]bool{}
+	add := func(lang string) {
+		if !seen[lang] {
+			seen[lang] = true
+			l.langs = append(l.langs, lang)
+		}
+	}
+	for _, lang := range requested {
+		add(lang)
+	}
+	for _, lang := range requested {
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			add(base)
+		}
+	}
+	add(constants.DefaultLanguage)
+	w.Header().Set("Content-Language", l.langs[0])
+	return &l
+}
+
+func (l *localizer) films(films ...*models.Film) error {
+	if len(films) == 0 {
+		return nil
+	}
+	ids := []int{}
+	for _, film := range films {
+		ids = append(ids, film.ID)
+	}
+	translations, err := db.Instance().GetFilmsTranslations(ids, l.langs)
+	if err != nil {
+		return err
+	}
+	byFilm := map[int]map[string]*models.FilmTranslation{}
+	for _, t := range translations {
+		if byFilm[t.FilmID] == nil {
+			byFilm[t.FilmID] = map[string]*models.FilmTranslation{}
+		}
+		byFilm[t.FilmID][t.Lang] = t
+	}
+	for _, film := range films {
+		for _, lang := range l.langs {
+			t, ok := byFilm[film.ID][lang]
+			if !ok {
+				continue
+			}
+			if t.Name != nil {
+				film.Name = t.Name
+			}
+			if t.Description != nil {
+				film.Description = t.Description
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (l *localizer) actors(actors ...*models.Actor) error {
+	if len(actors) == 0 {
+		return nil
+	}
+	ids := []int{}
+	for _, actor := range actors {
+		ids = append(ids, actor.ID)
+	}
+	translations, err := db.Instance().GetActorsTranslations(ids, l.langs)
+	if err != nil {
+		return err
+	}
+	byActor := map[int]map[string]*models.ActorTranslation{}
+	for _, t := range translations {
+		if byActor[t.ActorID] == nil {
+			byActor[t.ActorID] = map[string]*models.ActorTranslation{}
+		}
+		byActor[t.ActorID][t.Lang] = t
+	}
+	base, _, _ := strings.Cut(l.langs[0], "-")
+	for _, actor := range actors {
+		translated := false
+		for _, lang := range l.langs {
+			t, ok := byActor[actor.ID][lang]
+			if !ok {
+				continue
+			}
+			if t.FirstName != nil {
+				actor.FirstName = t.FirstName
+			}
+			if t.LastName != nil {
+				actor.LastName = t.LastName
+			}
+			translated = true
+			break
+		}
+		if !translated && !constants.CyrillicLanguages[base] {
+			actor.FirstName = transliterated(actor.FirstName)
+			actor.LastName = transliterated(actor.LastName)
+		}
+	}
+
+	// The primary alias is chosen explicitly, so it is shown regardless of
+	// the requested language.
+	aliases, err := db.Instance().GetPrimaryAliases(ids)
+	if err != nil {
+		return err
+	}
+	for _, actor := range actors {
+		if name, ok := aliases[actor.ID]; ok {
+			actor.DisplayName = &name
+		}
+	}
+	return nil
+}
+
+func transliterated(s *string) *string {
+	if s == nil || !utils.HasCyrillic(*s) {
+		return s
+	}
+	res := utils.Transliterate(*s)
+	return &res
+}
+
+func (s *Server) filmTranslationsHandler(filmID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if filmID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.getFilmTranslations(filmID, w, r)
+		return
+	}
+	lang, ok := translationLanguage(segments)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid language"))
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.putFilmTranslation(filmID, lang, w, r)
+	case http.MethodDelete:
+		s.deleteFilmTranslation(filmID, lang, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+func (s *Server) actorTranslationsHandler(actorID int, segments []string, w http.ResponseWriter, r *http.Request) {
+	if actorID < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.getActorTranslations(actorID, w, r)
+		return
+	}
+	lang, ok := translationLanguage(segments)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid language"))
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.putActorTranslation(actorID, lang, w, r)
+	case http.MethodDelete:
+		s.deleteActorTranslation(actorID, lang, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Unexpected HTTP method"))
+	}
+}
+
+func translationLanguage(segments []string) (string, bool) {
+	if len(segments) != 1 {
+		return "", false
+	}
+	return utils.NormalizeLanguage(segments[0])
+}
+
+// @Summary Get film translations
+// @Tags film
+// @Description Все переводы названия и описания фильма
+// @ID get-film-translations
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetFilmTranslations
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/translations [get]
+func (*Server) getFilmTranslations(filmID int, w http.ResponseWriter, r *http.Request) {
+	translations, err := db.Instance().GetFilmsTranslations([]int{filmID}, nil)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"translations": translations})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Set film translation
+// @Tags film
+// @Description Создание или замена перевода названия и описания фильма на указанный язык
+// @ID put-film-translation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param lang path string true "код языка, например en или en-us"
+// @Param requestBody body models.FilmTranslationPut true "Перевод"
+// @Success 200 {string} string "translation saved"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/translations/{lang} [put]
+func (*Server) putFilmTranslation(filmID int, lang string, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	translation := models.FilmTranslation{}
+	err = json.Unmarshal(body, &translation)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	translation.FilmID = filmID
+	translation.Lang = lang
+	if translation.Name == nil && translation.Description == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("name or description should be provided"))
+		return
+	}
+	if translation.Name != nil && (len(*translation.Name) < 1 || len(*translation.Name) > 150) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("the length of the film name must be at least 1 and no more than 150 characters"))
+		return
+	}
+	if translation.Description != nil && len(*translation.Description) > 1500 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("film's description len should not exceed 1500 symbols"))
+		return
+	}
+	err = db.Instance().SetFilmTranslation(&translation)
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("translation saved"))
+}
+
+// @Summary Delete film translation
+// @Tags film
+// @Description Удаление перевода фильма на указанный язык
+// @ID delete-film-translation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Param lang path string true "код языка"
+// @Success 200 {string} string "translation deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /film/{id}/translations/{lang} [delete]
+func (*Server) deleteFilmTranslation(filmID int, lang string, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().DeleteFilmTranslation(filmID, lang)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("translation deleted"))
+}
+
+// @Summary Get actor translations
+// @Tags actor
+// @Description Все переводы имени актера
+// @ID get-actor-translations
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "id"
+// @Success 200 {object} models.GetActorTranslations
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/translations [get]
+func (*Server) getActorTranslations(actorID int, w http.ResponseWriter, r *http.Request) {
+	translations, err := db.Instance().GetActorsTranslations([]int{actorID}, nil)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get translations from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(map[string]any{"translations": translations})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Set actor translation
+// @Tags actor
+// @Description Создание или замена перевода имени актера на указанный язык. Если перевода нет, для языков с латиницей имя транслитерируется
+// @ID put-actor-translation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path int true "id"
+// @Param lang path string true "код языка, например en или en-us"
+// @Param requestBody body models.ActorTranslationPut true "Перевод"
+// @Success 200 {string} string "translation saved"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/translations/{lang} [put]
+func (*Server) putActorTranslation(actorID int, lang string, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	translation := models.ActorTranslation{}
+	err = json.Unmarshal(body, &translation)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unmarshal request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	translation.ActorID = actorID
+	translation.Lang = lang
+	if translation.FirstName == nil && translation.LastName == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("first name or last name should be provided"))
+		return
+	}
+	if translation.FirstName != nil && (len(*translation.FirstName) == 0 || len(*translation.FirstName) > 100) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("first name length should be at least 1 and no more than 100 characters"))
+		return
+	}
+	if translation.LastName != nil && (len(*translation.LastName) == 0 || len(*translation.LastName) > 100) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("last name length should be at least 1 and no more than 100 characters"))
+		return
+	}
+	err = db.Instance().SetActorTranslation(&translation)
+	if db.IsForeignKeyViolation(err) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot add value to db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("translation saved"))
+}
+
+// @Summary Delete actor translation
+// @Tags actor
+// @Description Удаление перевода имени актера на указанный язык
+// @ID delete-actor-translation
+// @Security BasicAuth
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path int true "id"
+// @Param lang path string true "код языка"
+// @Success 200 {string} string "translation deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /actor/{id}/translations/{lang} [delete]
+func (*Server) deleteActorTranslation(actorID int, lang string, w http.ResponseWriter, r *http.Request) {
+	n, err := db.Instance().DeleteActorTranslation(actorID, lang)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("translation deleted"))
+}
+
+func (l *localizer) filmResponds(responds ...*models.FilmRespond) error {
+	films := []*models.Film{}
+	actors := []*models.Actor{}
+	for _, respond := range responds {
+		films = append(films, respond.Film)
+		actors = append(actors, respond.Actors...)
+		for _, relation := range respond.Relations {
+			films = append(films, relation.Film)
+		}
+	}
+	err := l.films(films...)
+	if err != nil {
+		return err
+	}
+	return l.actors(actors...)
+}
+
+func (l *localizer) actorResponds(responds ...*models.ActorRespond) error {
+	films := []*models.Film{}
+	actors := []*models.Actor{}
+	for _, respond := range responds {
+		actors = append(actors, respond.Actor)
+		for _, film := range respond.Films {
+			films = append(films, film.Film)
+		}
+	}
+	err := l.films(films...)
+	if err != nil {
+		return err
+	}
+	return l.actors(actors...)
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewLocalizer(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		want           []string
+	}{
+		{"default", "/film/5", "", []string{"ru"}},
+		{"lang query on an entity", "/film/5?lang=en", "", []string{"en", "ru"}},
+		{"lang query with as_of", "/actor/7?as_of=01.01.2020&lang=EN_us", "", []string{"en-us", "en", "ru"}},
+		{"lang query before header", "/film/5?lang=de", "fr;q=0.5, en", []string{"de", "en", "fr", "ru"}},
+		{"invalid lang query", "/film/5?lang=%2A", "en-GB", []string{"en-gb", "en", "ru"}},
+		{"default language requested", "/film/5?lang=ru", "en", []string{"ru", "en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			l := newLocalizer(w, r)
+			if !reflect.DeepEqual(l.langs, tt.want) {
+				t.Errorf("langs = %v, want %v", l.langs, tt.want)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.want[0] {
+				t.Errorf("Content-Language = %q, want %q", got, tt.want[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// TrashRules declares permissions required by /trash/ routes. Deleted
+// records are not shown even for reading without the permission.
+var TrashRules = []middleware.Rule{
+	{Path: "/trash/**", Permission: constants.PermissionTrashManage},
+}
+
+func (s *Server) TrashHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	if len(segments) == 1 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Unexpected HTTP method"))
+			return
+		}
+		s.getTrash(w, r)
+		return
+	}
+	entity := models.TrashEntity(segments[1])
+	if entity != constants.TrashEntityFilm && entity != constants.TrashEntityActor {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	id, err := utils.ParseSegmentID(segments, 2)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid id"))
+		return
+	}
+	switch {
+	case len(segments) == 4 && segments[3] == "restore" && r.Method == http.MethodPost:
+		s.restoreFromTrash(entity, id, w, r)
+	case len(segments) == 3 && r.Method == http.MethodDelete:
+		s.purgeFromTrash(entity, id, w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get trash
+// @Tags trash
+// @Description Удаленные фильмы и актеры, которые еще можно восстановить. Требуется право trash:manage
+// @ID get-trash
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TrashRespond
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /trash/ [get]
+func (*Server) getTrash(w http.ResponseWriter, r *http.Request) {
+	films, err := db.Instance().GetDeletedFilms()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get deleted films from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	actors, err := db.Instance().GetDeletedActors()
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get deleted actors from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	res, err := json.Marshal(models.TrashRespond{Films: films, Actors: actors})
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// @Summary Restore from trash
+// @Tags trash
+// @Description Восстановление удаленного фильма вместе с актерским составом или актера вместе с его ролями
+// @ID restore-from-trash
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param entity path string true "film или actor"
+// @Param id path int true "id"
+// @Success 200 {string} string "film restored"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /trash/{entity}/{id}/restore [post]
+func (*Server) restoreFromTrash(entity models.TrashEntity, id int, w http.ResponseWriter, r *http.Request) {
+	err := db.Instance().InTx(func(tx *db.DBProvider) error {
+		restore := tx.RestoreFilm
+		if entity == constants.TrashEntityActor {
+			restore = tx.RestoreActor
+		}
+		n, err := restore(id)
+		if err != nil {
+			return fmt.Errorf("cannot restore value: %w", err)
+		}
+		if n == 0 {
+			return db.ErrNotFound
+		}
+		if entity == constants.TrashEntityActor {
+			actor, err := loadActorSnapshot(tx, id)
+			if err != nil {
+				return fmt.Errorf("cannot get value from db: %w", err)
+			}
+			return recordRevision(tx, r, constants.RevisionEntityActor, id, constants.RevisionRestore, nil, actor)
+		}
+		film, err := loadFilmSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get value from db: %w", err)
+		}
+		return recordRevision(tx, r, constants.RevisionEntityFilm, id, constants.RevisionRestore, nil, film)
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR %v %v: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf("%v restored", entity)))
+}
+
+// @Summary Purge from trash
+// @Tags trash
+// @Description Окончательное удаление фильма или актера из корзины без ожидания окончания срока хранения
+// @ID purge-from-trash
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param entity path string true "film или actor"
+// @Param id path int true "id"
+// @Success 200 {string} string "film purged"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /trash/{entity}/{id} [delete]
+func (s *Server) purgeFromTrash(entity models.TrashEntity, id int, w http.ResponseWriter, r *http.Request) {
+	purge := db.Instance().PurgeFilm
+	if entity == constants.TrashEntityActor {
+		purge = db.Instance().PurgeActor
+	}
+	n, err := purge(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	if entity == constants.TrashEntityActor {
+		s.removeImage(constants.ImageEntityActor, id)
+	} else {
+		s.removeImage(constants.ImageEntityFilm, id)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf("%v purged", entity)))
+}
+
+// RunTrashRetention permanently deletes films and actors that stayed in the
+// trash longer than retention. It blocks, checking the trash every interval.
+func (s *Server) RunTrashRetention(retention time.Duration, interval time.Duration) {
+	for {
+		s.purgeTrash(retention)
+		time.Sleep(interval)
+	}
+}
+
+func (s *Server) purgeTrash(retention time.Duration) {
+	films, actors, err := db.Instance().PurgeDeleted(time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("ERROR cannot purge trash: %v", err)
+		return
+	}
+	for _, id := range films {
+		s.removeImage(constants.ImageEntityFilm, id)
+	}
+	for _, id := range actors {
+		s.removeImage(constants.ImageEntityActor, id)
+	}
+	if len(films)+len(actors) > 0 {
+		log.Printf("purged %v films and %v actors from trash", len(films), len(actors))
+	}
+}
+
+// filmExists writes 404 if the film does not exist or is in the trash.
+func filmExists(id int, w http.ResponseWriter, r *http.Request) bool {
+	return checkEntityExists(db.Instance().FilmExists, id, w, r)
+}
+
+// actorExists writes 404 if the actor does not exist or is in the trash.
+func actorExists(id int, w http.ResponseWriter, r *http.Request) bool {
+	return checkEntityExists(db.Instance().ActorExists, id, w, r)
+}
+
+func checkEntityExists(exists func(int) (bool, error), id int, w http.ResponseWriter, r *http.Request) bool {
+	ok, err := exists(id)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get value from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return false
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return false
+	}
+	return true
+}
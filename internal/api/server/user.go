@@ -0,0 +1,392 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/api/middleware"
+	"github.com/ffdb42/vk_trainee_task/internal/auth"
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
+)
+
+// UserRules declares permissions required by /users/ routes.
+var UserRules = []middleware.Rule{
+	{Path: "/users/**", Permission: constants.PermissionUsersManage},
+}
+
+func (s *Server) UserHandler(w http.ResponseWriter, r *http.Request) {
+	segments := utils.PathSegments(r.URL.Path)
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		s.getUsers(w, r)
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		s.getUser(segments[1], w, r)
+	case len(segments) == 2 && r.Method == http.MethodDelete:
+		s.deleteUser(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "role" && r.Method == http.MethodPut:
+		s.putUserRole(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "disable" && r.Method == http.MethodPost:
+		s.disableUser(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "enable" && r.Method == http.MethodPost:
+		s.enableUser(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "unlock" && r.Method == http.MethodPost:
+		s.unlockUser(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "force-password-reset" && r.Method == http.MethodPost:
+		s.forcePasswordReset(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "sessions" && r.Method == http.MethodGet:
+		s.getUserSessions(segments[1], w, r)
+	case len(segments) == 3 && segments[2] == "sessions" && r.Method == http.MethodDelete:
+		s.revokeUserSessions(segments[1], w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}
+}
+
+// @Summary Get users
+// @Tags users
+// @Description Список пользователей с поиском по фрагменту логина и фильтрами по роли и блокировке. Требуется право users:manage
+// @ID get-users
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param search query string false "фрагмент логина"
+// @Param role query string false "роль"
+// @Param disabled query bool false "только заблокированные или только активные"
+// @Success 200 {object} models.GetUsers
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/ [get]
+func (*Server) getUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.UsersFilter{
+		Search: strings.TrimSpace(query.Get("search")),
+		Role:   query.Get("role"),
+	}
+	if query.Has("disabled") {
+		disabled, err := strconv.ParseBool(query.Get("disabled"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid disabled"))
+			return
+		}
+		filter.Disabled = &disabled
+	}
+	users, err := db.Instance().GetUsers(&filter)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get users from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	writeUserJSON(models.GetUsers{Users: users}, w, r)
+}
+
+// @Summary Get user
+// @Tags users
+// @Description Пользователь по логину. Требуется право users:manage
+// @ID get-user
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param login path string true "логин"
+// @Success 200 {object} models.UserInfo
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login} [get]
+func (*Server) getUser(login string, w http.ResponseWriter, r *http.Request) {
+	user, err := db.Instance().GetUserInfo(login)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+		return
+	}
+	writeUserJSON(user, w, r)
+}
+
+// @Summary Change user role
+// @Tags users
+// @Description Назначение роли пользователю, то же что PUT /role/{name}/users/{login}. Требуется право users:manage
+// @ID put-user-role
+// @Security BasicAuth
+// @Security BearerAuth
+// @Accept json
+// @Param login path string true "логин"
+// @Param requestBody body models.UserRolePut true "Роль"
+// @Success 200 {string} string "role assigned"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/role [put]
+func (s *Server) putUserRole(login string, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot read request body: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot get request body"))
+		return
+	}
+	rolePut := models.UserRolePut{}
+	err = json.Unmarshal(body, &rolePut)
+	if err != nil || rolePut.Role == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("role was not provided"))
+		return
+	}
+	s.assignRole(rolePut.Role, login, w, r)
+}
+
+// @Summary Disable user
+// @Tags users
+// @Description Блокировка учетной записи. Заблокированный пользователь не может войти ни по паролю, ни по токену, ни по API ключу, его refresh токены отзываются. Свою учетную запись заблокировать нельзя. Требуется право users:manage
+// @ID disable-user
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "user disabled"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/disable [post]
+func (s *Server) disableUser(login string, w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(login, true, w, r)
+}
+
+// @Summary Enable user
+// @Tags users
+// @Description Снятие блокировки с учетной записи. Требуется право users:manage
+// @ID enable-user
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "user enabled"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/enable [post]
+func (s *Server) enableUser(login string, w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(login, false, w, r)
+}
+
+func (*Server) setUserDisabled(login string, disabled bool, w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.CurrentUser(r)
+	if login == currentUser.Name {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot disable own account"))
+		return
+	}
+	user, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	err := db.Instance().SetUserDisabled(user.ID, disabled, currentUser.Name)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	w.WriteHeader(http.StatusOK)
+	if disabled {
+		w.Write([]byte("user disabled"))
+	} else {
+		w.Write([]byte("user enabled"))
+	}
+}
+
+// @Summary Unlock user
+// @Tags users
+// @Description Снятие блокировки входа, наложенной после неудачных попыток, и сброс счетчика попыток. Блокировка по адресу клиента не снимается. Требуется право users:manage
+// @ID unlock-user
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "user unlocked"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/unlock [post]
+func (*Server) unlockUser(login string, w http.ResponseWriter, r *http.Request) {
+	_, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	err := auth.Unlock(login)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot unlock user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	log.Printf("%v unlocked by %v", login, middleware.CurrentUser(r).Name)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("user unlocked"))
+}
+
+// @Summary Force password reset
+// @Tags users
+// @Description Требование сменить пароль. До смены пароля пользователь получает 403, его refresh токены и сессии отзываются. Требуется право users:manage
+// @ID force-password-reset
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "password reset required"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/force-password-reset [post]
+func (*Server) forcePasswordReset(login string, w http.ResponseWriter, r *http.Request) {
+	user, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	err := db.Instance().ForcePasswordReset(user.ID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot update user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("password reset required"))
+}
+
+// @Summary Get user sessions
+// @Tags users
+// @Description Активные браузерные сессии пользователя. Требуется право users:manage
+// @ID get-user-sessions
+// @Security BasicAuth
+// @Security BearerAuth
+// @Produce json
+// @Param login path string true "логин"
+// @Success 200 {object} models.GetSessions
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/sessions [get]
+func (*Server) getUserSessions(login string, w http.ResponseWriter, r *http.Request) {
+	user, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	writeSessions(user.ID, w, r)
+}
+
+// @Summary Revoke user sessions
+// @Tags users
+// @Description Завершение всех браузерных сессий пользователя. Требуется право users:manage
+// @ID revoke-user-sessions
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "sessions revoked"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login}/sessions [delete]
+func (*Server) revokeUserSessions(login string, w http.ResponseWriter, r *http.Request) {
+	user, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	revokeSessions(user.ID, 0, w, r)
+}
+
+// @Summary Delete user
+// @Tags users
+// @Description Удаление учетной записи вместе с ее refresh токенами, сессиями и API ключами. Выданные ранее access токены перестают приниматься. Авторство ревизий сохраняется. Свою учетную запись удалить нельзя. Требуется право users:manage
+// @ID delete-user
+// @Security BasicAuth
+// @Security BearerAuth
+// @Param login path string true "логин"
+// @Success 200 {string} string "user deleted"
+// @Failure 400 {string} string "error string"
+// @Failure 401 {string} string "unauthtorized"
+// @Failure 403 {string} string "forbidden"
+// @Failure 404 {string} string "not found"
+// @Failure 500 {string} string "internal server error"
+// @Router /users/{login} [delete]
+func (*Server) deleteUser(login string, w http.ResponseWriter, r *http.Request) {
+	if login == middleware.CurrentUser(r).Name {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("cannot delete own account"))
+		return
+	}
+	user, ok := findUser(login, w, r)
+	if !ok {
+		return
+	}
+	_, err := db.Instance().DeleteUser(user.ID)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot delete user: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	auth.InvalidateAccounts()
+	auth.ForgetCredentials(user.ID)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("user deleted"))
+}
+
+// findUser writes 404 if there is no user with the login.
+func findUser(login string, w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := db.Instance().GetUser(login)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot get user from db: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return nil, false
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("user %v not found", login)))
+		return nil, false
+	}
+	return user, true
+}
+
+func writeUserJSON(respond any, w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(respond)
+	if err != nil {
+		log.Printf("ERROR %v %v: cannot marshal json: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal server error"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
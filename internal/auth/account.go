@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var (
+	ErrAccountDisabled       = errors.New("account is disabled")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrCredentialsChanged    = errors.New("password was changed, sign in again")
+)
+
+// restrictedCache keeps disabled users, users who have to reset the password
+// and users who changed it recently, so that bearer tokens and API keys of
+// such users are rejected without a database lookup per request.
+var restrictedCache struct {
+	sync.Mutex
+	users    map[int]*models.User
+	loadedAt time.Time
+}
+
+// CheckAccount returns an error if the account can not be used with
+// credentials issued at issuedAt.
+func CheckAccount(user *models.User, issuedAt time.Time) error {
+	if user.Disabled {
+		return ErrAccountDisabled
+	}
+	if user.MustResetPassword {
+		return ErrPasswordResetRequired
+	}
+	restrictedCache.Lock()
+	defer restrictedCache.Unlock()
+	if restrictedCache.users == nil || time.Since(restrictedCache.loadedAt) > constants.PermissionCacheTTL {
+		// Access tokens issued before older changes have expired anyway.
+		users, err := db.Instance().GetRestrictedUsers(time.Now().Add(-constants.AccessTokenTTL))
+		if err != nil {
+			return err
+		}
+		restrictedCache.users = users
+		restrictedCache.loadedAt = time.Now()
+	}
+	restricted, ok := restrictedCache.users[user.ID]
+	if !ok {
+		return nil
+	}
+	switch {
+	case restricted.Disabled:
+		return ErrAccountDisabled
+	case restricted.MustResetPassword:
+		return ErrPasswordResetRequired
+	case restricted.CredentialsChangedAt != nil && issuedAt.Before(*restricted.CredentialsChangedAt):
+		return ErrCredentialsChanged
+	}
+	return nil
+}
+
+// InvalidateAccounts drops cached restricted accounts after a user is
+// disabled, enabled, deleted, gets another role, has to reset the password or
+// changes it.
+func InvalidateAccounts() {
+	restrictedCache.Lock()
+	defer restrictedCache.Unlock()
+	restrictedCache.users = nil
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+const (
+	apiKeyPrefix    = "vkk_"
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyExpired  = errors.New("api key expired")
+	ErrIPNotAllowed   = errors.New("api key is not allowed from this address")
+	ErrScopeForbidden = errors.New("api key scope does not allow this request")
+)
+
+// Scopes lists all scopes an API key can be granted.
+var Scopes = []string{
+	constants.ScopeFilmsRead,
+	constants.ScopeFilmsWrite,
+	constants.ScopeActorsRead,
+	constants.ScopeActorsWrite,
+	constants.ScopeFranchisesRead,
+	constants.ScopeFranchisesWrite,
+	constants.ScopeAwardsRead,
+	constants.ScopeAwardsWrite,
+	constants.ScopeSearch,
+}
+
+// routeScopes maps the first segment of the path to the read and write scopes
+// of the resource. Routes missing here can not be used with API keys.
+var routeScopes = map[string][2]string{
+	"film":      {constants.ScopeFilmsRead, constants.ScopeFilmsWrite},
+	"actor":     {constants.ScopeActorsRead, constants.ScopeActorsWrite},
+	"franchise": {constants.ScopeFranchisesRead, constants.ScopeFranchisesWrite},
+	"award":     {constants.ScopeAwardsRead, constants.ScopeAwardsWrite},
+	"ceremony":  {constants.ScopeAwardsRead, constants.ScopeAwardsWrite},
+	"search":    {constants.ScopeSearch, ""},
+}
+
+// RequiredScope returns the scope an API key needs for the request.
+func RequiredScope(r *http.Request) (string, bool) {
+	resource, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	scopes, ok := routeScopes[resource]
+	if !ok {
+		return "", false
+	}
+	if r.Method == http.MethodGet {
+		return scopes[0], true
+	}
+	return scopes[1], scopes[1] != ""
+}
+
+// NewAPIKey returns a random API key, its prefix shown in listings and its
+// hash. Only the prefix and the hash are stored.
+func NewAPIKey() (string, string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key := apiKeyPrefix + encode(buf)
+	return key, key[:apiKeyPrefixLen], HashToken(key), nil
+}
+
+// ParseAllowedIP checks that the entry of an IP allow-list is an address or
+// a CIDR range and returns it in canonical form.
+func ParseAllowedIP(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return "", err
+		}
+		return prefix.Masked().String(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// AuthenticateAPIKey returns the owner of the key if the key is active,
+// allowed from the address of the request and grants the required scope.
+func AuthenticateAPIKey(key string, r *http.Request) (*models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := db.Instance().UseAPIKey(HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if apiKey.ExpiresAt != nil && !time.Now().Before(*apiKey.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	if !ipAllowed(apiKey.AllowedIPs, r.RemoteAddr) {
+		return nil, ErrIPNotAllowed
+	}
+	scope, ok := RequiredScope(r)
+	if !ok || !hasScope(apiKey.Scopes, scope) {
+		return nil, ErrScopeForbidden
+	}
+	return &models.User{ID: apiKey.UserID, Name: apiKey.Login, Role: apiKey.Role}, nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ipAllowed reports whether the remote address matches the allow-list. An
+// empty allow-list allows any address.
+func ipAllowed(allowed []string, remoteAddr string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range allowed {
+		if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+		if entryAddr, err := netip.ParseAddr(entry); err == nil && entryAddr == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// credentialCache remembers successful password verifications, so that Basic
+// auth does not look the user up and hash the password on every request.
+// Entries are keyed by an HMAC of the login and the password under a random
+// key of the process, the password itself is never kept. The least recently
+// used entry is evicted when the cache is full.
+var credentialCache struct {
+	sync.Mutex
+	key     []byte
+	entries map[string]*list.Element
+	// order holds *credentialEntry, the most recently used first.
+	order *list.List
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type credentialEntry struct {
+	key       string
+	user      models.User
+	expiresAt time.Time
+}
+
+// CredentialCacheStats are counters of the credential cache since start.
+type CredentialCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// credentialKey returns the cache key of the credentials, or "" if the cache
+// can not be used.
+func credentialKey(name, pass string) string {
+	if credentialCache.key == nil {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return ""
+		}
+		credentialCache.key = key
+		credentialCache.entries = map[string]*list.Element{}
+		credentialCache.order = list.New()
+	}
+	mac := hmac.New(sha256.New, credentialCache.key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(pass))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cachedCredentials returns the user if the credentials were verified within
+// CredentialCacheTTL, otherwise nil.
+func cachedCredentials(name, pass string) *models.User {
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	key := credentialKey(name, pass)
+	element, ok := credentialCache.entries[key]
+	if key == "" || !ok {
+		credentialCache.misses.Add(1)
+		return nil
+	}
+	entry := element.Value.(*credentialEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		credentialCache.order.Remove(element)
+		delete(credentialCache.entries, key)
+		credentialCache.misses.Add(1)
+		return nil
+	}
+	credentialCache.order.MoveToFront(element)
+	credentialCache.hits.Add(1)
+	user := entry.user
+	return &user
+}
+
+// cacheCredentials remembers verified credentials of the user. The password
+// hash is not cached.
+func cacheCredentials(name, pass string, user *models.User) {
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	key := credentialKey(name, pass)
+	if key == "" {
+		return
+	}
+	entry := &credentialEntry{key: key, user: *user, expiresAt: time.Now().Add(constants.CredentialCacheTTL)}
+	entry.user.Password = ""
+	if element, ok := credentialCache.entries[key]; ok {
+		element.Value = entry
+		credentialCache.order.MoveToFront(element)
+		return
+	}
+	credentialCache.entries[key] = credentialCache.order.PushFront(entry)
+	for credentialCache.order.Len() > constants.CredentialCacheSize {
+		oldest := credentialCache.order.Back()
+		credentialCache.order.Remove(oldest)
+		delete(credentialCache.entries, oldest.Value.(*credentialEntry).key)
+		credentialCache.evictions.Add(1)
+	}
+}
+
+// ForgetCredentials drops cached credentials of the user after the password
+// or the role is changed or the account is disabled. Other app instances
+// drop them after CredentialCacheTTL.
+func ForgetCredentials(userID int) {
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	if credentialCache.order == nil {
+		return
+	}
+	for element := credentialCache.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*credentialEntry)
+		if entry.user.ID == userID {
+			credentialCache.order.Remove(element)
+			delete(credentialCache.entries, entry.key)
+		}
+		element = next
+	}
+}
+
+// GetCredentialCacheStats returns the counters of the credential cache.
+func GetCredentialCacheStats() CredentialCacheStats {
+	credentialCache.Lock()
+	size := len(credentialCache.entries)
+	credentialCache.Unlock()
+	return CredentialCacheStats{
+		Hits:      credentialCache.hits.Load(),
+		Misses:    credentialCache.misses.Load(),
+		Evictions: credentialCache.evictions.Load(),
+		Size:      size,
+	}
+}
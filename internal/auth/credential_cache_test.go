@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func resetCredentialCache() {
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	credentialCache.key = nil
+	credentialCache.entries = nil
+	credentialCache.order = nil
+	credentialCache.hits.Store(0)
+	credentialCache.misses.Store(0)
+	credentialCache.evictions.Store(0)
+}
+
+func TestCredentialCache(t *testing.T) {
+	alice := &models.User{ID: 1, Name: "alice", Role: constants.AdminRole, Password: "hash"}
+	bob := &models.User{ID: 2, Name: "bob", Role: constants.ViewerRole, Password: "hash"}
+	tests := []struct {
+		name      string
+		prepare   func()
+		login     string
+		pass      string
+		wantID    int
+		wantStats CredentialCacheStats
+	}{
+		{
+			name:      "empty",
+			prepare:   func() {},
+			login:     "alice",
+			pass:      "secret",
+			wantStats: CredentialCacheStats{Misses: 1},
+		},
+		{
+			name:      "cached",
+			prepare:   func() { cacheCredentials("alice", "secret", alice) },
+			login:     "alice",
+			pass:      "secret",
+			wantID:    alice.ID,
+			wantStats: CredentialCacheStats{Hits: 1, Size: 1},
+		},
+		{
+			name:      "wrong password",
+			prepare:   func() { cacheCredentials("alice", "secret", alice) },
+			login:     "alice",
+			pass:      "secret2",
+			wantStats: CredentialCacheStats{Misses: 1, Size: 1},
+		},
+		{
+			name:      "login and password are not concatenated",
+			prepare:   func() { cacheCredentials("alice", "secret", alice) },
+			login:     "alic",
+			pass:      "esecret",
+			wantStats: CredentialCacheStats{Misses: 1, Size: 1},
+		},
+		{
+			name: "expired",
+			prepare: func() {
+				cacheCredentials("alice", "secret", alice)
+				credentialCache.order.Front().Value.(*credentialEntry).expiresAt = time.Now().Add(-time.Second)
+			},
+			login:     "alice",
+			pass:      "secret",
+			wantStats: CredentialCacheStats{Misses: 1},
+		},
+		{
+			name: "forgotten user",
+			prepare: func() {
+				cacheCredentials("alice", "secret", alice)
+				cacheCredentials("alice", "other", alice)
+				cacheCredentials("bob", "secret", bob)
+				ForgetCredentials(alice.ID)
+			},
+			login:     "alice",
+			pass:      "secret",
+			wantStats: CredentialCacheStats{Misses: 1, Size: 1},
+		},
+		{
+			name: "other users are kept",
+			prepare: func() {
+				cacheCredentials("alice", "secret", alice)
+				cacheCredentials("bob", "secret", bob)
+				ForgetCredentials(alice.ID)
+			},
+			login:     "bob",
+			pass:      "secret",
+			wantID:    bob.ID,
+			wantStats: CredentialCacheStats{Hits: 1, Size: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCredentialCache()
+			tt.prepare()
+			user := cachedCredentials(tt.login, tt.pass)
+			switch {
+			case tt.wantID == 0 && user != nil:
+				t.Errorf("user = %+v, want nil", user)
+			case tt.wantID != 0 && (user == nil || user.ID != tt.wantID):
+				t.Errorf("user = %+v, want user %v", user, tt.wantID)
+			case user != nil && user.Password != "":
+				t.Errorf("password hash is cached")
+			}
+			if stats := GetCredentialCacheStats(); stats != tt.wantStats {
+				t.Errorf("stats = %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestCredentialCacheEviction(t *testing.T) {
+	resetCredentialCache()
+	user := &models.User{ID: 1, Name: "alice"}
+	for i := 0; i < constants.CredentialCacheSize; i++ {
+		cacheCredentials("user"+strconv.Itoa(i), "secret", user)
+	}
+	// Using the oldest entry makes the second one the least recently used.
+	if cachedCredentials("user0", "secret") == nil {
+		t.Fatalf("user0 is not cached")
+	}
+	cacheCredentials("new", "secret", user)
+
+	stats := GetCredentialCacheStats()
+	if stats.Size != constants.CredentialCacheSize || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want size %v and 1 eviction", stats, constants.CredentialCacheSize)
+	}
+	tests := []struct {
+		login  string
+		cached bool
+	}{
+		{"user0", true},
+		{"user1", false},
+		{"user2", true},
+		{"user" + strconv.Itoa(constants.CredentialCacheSize-1), true},
+		{"new", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.login, func(t *testing.T) {
+			if cached := cachedCredentials(tt.login, "secret") != nil; cached != tt.cached {
+				t.Errorf("cached = %v, want %v", cached, tt.cached)
+			}
+		})
+	}
+}
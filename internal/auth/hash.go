@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var errUnknownHash = errors.New("unknown password hash format")
+
+// HashParams describes how new passwords are hashed. Stored hashes carry
+// their own parameters, bcrypt in its standard format and argon2id in the
+// PHC string format, so they can be verified after the parameters change.
+type HashParams struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB.
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+func (p *HashParams) hash(pass string) (string, error) {
+	if p.Algorithm == HashBcrypt {
+		hashedPass, err := bcrypt.GenerateFromPassword([]byte(pass), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedPass), nil
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(pass), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%v$%v",
+		argon2.Version,
+		p.Argon2Memory,
+		p.Argon2Time,
+		p.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// outdated reports whether the hash was made with other parameters.
+func (p *HashParams) outdated(hashedPass string) bool {
+	if strings.HasPrefix(hashedPass, "$argon2id$") {
+		if p.Algorithm != HashArgon2id {
+			return true
+		}
+		hash, err := parseArgon2id(hashedPass)
+		return err != nil || hash.memory != p.Argon2Memory || hash.time != p.Argon2Time || hash.threads != p.Argon2Threads
+	}
+	if p.Algorithm != HashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPass))
+	return err != nil || cost != p.BcryptCost
+}
+
+// verifyPassword compares the password with a hash made by any supported
+// algorithm.
+func verifyPassword(hashedPass, pass string) (bool, error) {
+	if !strings.HasPrefix(hashedPass, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(pass))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	hash, err := parseArgon2id(hashedPass)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(pass), hash.salt, hash.time, hash.memory, hash.threads, uint32(len(hash.key)))
+	return subtle.ConstantTimeCompare(key, hash.key) == 1, nil
+}
+
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2id(hashedPass string) (*argon2idHash, error) {
+	parts := strings.Split(hashedPass, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errUnknownHash
+	}
+	version := 0
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errUnknownHash
+	}
+	hash := argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.time, &hash.threads); err != nil {
+		return nil, errUnknownHash
+	}
+	var err error
+	hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, errUnknownHash
+	}
+	hash.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash.key) == 0 {
+		return nil, errUnknownHash
+	}
+	return &hash, nil
+}
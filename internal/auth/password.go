@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// PasswordPolicy holds requirements for new passwords and the parameters
+// they are hashed with.
+type PasswordPolicy struct {
+	MinLength int
+	// Blocklist holds common and breached passwords in lower case.
+	Blocklist map[string]bool
+	Hash      HashParams
+}
+
+// maxLength is the longest password accepted by bcrypt.
+func (p *PasswordPolicy) maxLength() int {
+	if p.Hash.Algorithm == HashBcrypt {
+		return 72
+	}
+	return 100
+}
+
+var policy = &PasswordPolicy{
+	MinLength: 8,
+	Blocklist: map[string]bool{},
+	Hash: HashParams{
+		Algorithm:     HashArgon2id,
+		BcryptCost:    12,
+		Argon2Memory:  64 * 1024,
+		Argon2Time:    3,
+		Argon2Threads: 2,
+	},
+}
+
+// LoadPasswordPolicy configures the policy from the environment:
+// PASSWORD_MIN_LENGTH, PASSWORD_BLOCKLIST_FILE with one password per line,
+// PASSWORD_HASH (argon2id or bcrypt), PASSWORD_BCRYPT_COST and
+// PASSWORD_ARGON2_MEMORY (KiB), PASSWORD_ARGON2_TIME, PASSWORD_ARGON2_THREADS.
+// Unset variables keep their defaults.
+func LoadPasswordPolicy() error {
+	loaded := *policy
+	var err error
+	if loaded.MinLength, err = envInt("PASSWORD_MIN_LENGTH", loaded.MinLength, 1, 72); err != nil {
+		return err
+	}
+	if env := os.Getenv("PASSWORD_HASH"); env != "" {
+		if env != HashArgon2id && env != HashBcrypt {
+			return fmt.Errorf("PASSWORD_HASH should be %v or %v", HashArgon2id, HashBcrypt)
+		}
+		loaded.Hash.Algorithm = env
+	}
+	if loaded.Hash.BcryptCost, err = envInt("PASSWORD_BCRYPT_COST", loaded.Hash.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost); err != nil {
+		return err
+	}
+	memory, err := envInt("PASSWORD_ARGON2_MEMORY", int(loaded.Hash.Argon2Memory), 8*1024, 4*1024*1024)
+	if err != nil {
+		return err
+	}
+	loaded.Hash.Argon2Memory = uint32(memory)
+	iterations, err := envInt("PASSWORD_ARGON2_TIME", int(loaded.Hash.Argon2Time), 1, 100)
+	if err != nil {
+		return err
+	}
+	loaded.Hash.Argon2Time = uint32(iterations)
+	threads, err := envInt("PASSWORD_ARGON2_THREADS", int(loaded.Hash.Argon2Threads), 1, 255)
+	if err != nil {
+		return err
+	}
+	loaded.Hash.Argon2Threads = uint8(threads)
+	if path := os.Getenv("PASSWORD_BLOCKLIST_FILE"); path != "" {
+		loaded.Blocklist, err = readBlocklist(path)
+		if err != nil {
+			return fmt.Errorf("cannot read PASSWORD_BLOCKLIST_FILE: %v", err)
+		}
+	}
+	policy = &loaded
+	return nil
+}
+
+func envInt(name string, value int, min int, max int) (int, error) {
+	env := os.Getenv(name)
+	if env == "" {
+		return value, nil
+	}
+	value, err := strconv.Atoi(env)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("%v should be a number from %v to %v", name, min, max)
+	}
+	return value, nil
+}
+
+func readBlocklist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	res := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			res[strings.ToLower(line)] = true
+		}
+	}
+	return res, scanner.Err()
+}
+
+// ValidatePassword checks the password against the policy.
+func ValidatePassword(pass string) error {
+	if len(pass) < policy.MinLength || len(pass) > policy.maxLength() {
+		return fmt.Errorf("pass length should be at least %v and no more than %v characters", policy.MinLength, policy.maxLength())
+	}
+	if policy.Blocklist[strings.ToLower(pass)] {
+		return errors.New("password is too common, choose another one")
+	}
+	return nil
+}
+
+// HashPassword hashes the password with the current policy.
+func HashPassword(pass string) (string, error) {
+	return policy.Hash.hash(pass)
+}
+
+// Login returns the user with the given name and password. Unknown names and
+// wrong passwords both result in ErrInvalidCredentials and are counted against
+// the account and the address, LockedError is returned while either of them
+// is locked. A hash made with outdated parameters is replaced with a hash
+// following the current policy. Credentials verified within
+// CredentialCacheTTL are taken from the credential cache instead of being
+// verified again, locks are checked either way.
+func Login(name, pass string, ip string) (*models.User, error) {
+	hasFailures, err := checkLocked(name, ip)
+	if err != nil {
+		return nil, err
+	}
+	user := cachedCredentials(name, pass)
+	if user == nil {
+		user, err = verifyCredentials(name, pass, ip)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasFailures {
+		if _, err := db.Instance().ClearLoginAttempts(AccountKey(name)); err != nil {
+			log.Printf("ERROR cannot clear failed logins of %v: %v", name, err)
+		}
+	}
+	return user, nil
+}
+
+// verifyCredentials checks the password against the stored hash and caches
+// the credentials on success.
+func verifyCredentials(name, pass string, ip string) (*models.User, error) {
+	user, err := db.Instance().GetUser(name)
+	if err != nil {
+		return nil, err
+	}
+	ok := false
+	if user != nil {
+		ok, err = verifyPassword(user.Password, pass)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		if err := recordFailure(name, ip); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if policy.Hash.outdated(user.Password) {
+		rehashPassword(user, pass)
+	}
+	cacheCredentials(name, pass, user)
+	return user, nil
+}
+
+// rehashPassword stores the password hashed with the current policy. Failures
+// are only logged, the user has already been authenticated.
+func rehashPassword(user *models.User, pass string) {
+	hashedPass, err := HashPassword(pass)
+	if err != nil {
+		log.Printf("ERROR cannot rehash password of %v: %v", user.Name, err)
+		return
+	}
+	err = db.Instance().UpdatePasswordHash(user.ID, user.Password, hashedPass)
+	if err != nil {
+		log.Printf("ERROR cannot rehash password of %v: %v", user.Name, err)
+		return
+	}
+	user.Password = hashedPass
+}
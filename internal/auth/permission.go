@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+)
+
+// Permissions lists all permissions that can be granted to roles.
+var Permissions = []string{
+	constants.PermissionFilmsRead,
+	constants.PermissionFilmsCreate,
+	constants.PermissionFilmsUpdate,
+	constants.PermissionFilmsDelete,
+	constants.PermissionActorsRead,
+	constants.PermissionActorsCreate,
+	constants.PermissionActorsUpdate,
+	constants.PermissionActorsDelete,
+	constants.PermissionActorsMerge,
+	constants.PermissionFranchisesRead,
+	constants.PermissionFranchisesWrite,
+	constants.PermissionAwardsRead,
+	constants.PermissionAwardsWrite,
+	constants.PermissionRevisionsRevert,
+	constants.PermissionTrashManage,
+	constants.PermissionAPIKeysManage,
+	constants.PermissionRolesManage,
+	constants.PermissionUsersManage,
+	constants.PermissionProposalsSubmit,
+	constants.PermissionProposalsModerate,
+	constants.PermissionMetricsRead,
+}
+
+// permissionCache keeps permissions of all roles so that authorizing a
+// request does not need a database lookup.
+var permissionCache struct {
+	sync.Mutex
+	roles    map[string]map[string]bool
+	loadedAt time.Time
+}
+
+// HasPermission reports whether the role grants the permission. The admin
+// role grants every permission.
+func HasPermission(role string, permission string) (bool, error) {
+	if role == constants.AdminRole {
+		return true, nil
+	}
+	permissionCache.Lock()
+	defer permissionCache.Unlock()
+	if permissionCache.roles == nil || time.Since(permissionCache.loadedAt) > constants.PermissionCacheTTL {
+		roles, err := db.Instance().GetRoles()
+		if err != nil {
+			return false, err
+		}
+		permissionCache.roles = map[string]map[string]bool{}
+		for _, role := range roles {
+			permissions := map[string]bool{}
+			for _, permission := range role.Permissions {
+				permissions[permission] = true
+			}
+			permissionCache.roles[role.Name] = permissions
+		}
+		permissionCache.loadedAt = time.Now()
+	}
+	return permissionCache.roles[role][permission], nil
+}
+
+// RolePermissions returns all permissions the role grants.
+func RolePermissions(role string) ([]string, error) {
+	res := []string{}
+	for _, permission := range Permissions {
+		ok, err := HasPermission(role, permission)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, permission)
+		}
+	}
+	return res, nil
+}
+
+// InvalidatePermissions drops cached permissions after roles are changed.
+func InvalidatePermissions() {
+	permissionCache.Lock()
+	defer permissionCache.Unlock()
+	permissionCache.roles = nil
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var (
+	ErrInvalidSession = errors.New("session is invalid or expired")
+	ErrInvalidCSRF    = errors.New("csrf token is missing or invalid")
+)
+
+// NewSession starts a browser session of the user. It returns the session
+// token for the cookie and the CSRF token.
+func NewSession(user *models.User, r *http.Request) (*models.Session, string, string, error) {
+	token, tokenHash, err := NewRefreshToken()
+	if err != nil {
+		return nil, "", "", err
+	}
+	csrfToken, csrfHash, err := NewRefreshToken()
+	if err != nil {
+		return nil, "", "", err
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.Session{
+		Login:     user.Name,
+		IP:        ClientIP(r),
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(constants.SessionAbsoluteTimeout),
+		UserID:    user.ID,
+		Role:      user.Role,
+		CSRFHash:  csrfHash,
+	}
+	err = db.Instance().AddSession(&session, tokenHash)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return &session, token, csrfToken, nil
+}
+
+// AuthenticateSession returns the active session with the token and extends
+// it. Unsafe requests have to carry the CSRF token of the session.
+func AuthenticateSession(token string, r *http.Request) (*models.Session, error) {
+	session, err := db.Instance().UseSession(HashToken(token), time.Now().Add(-constants.SessionIdleTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidSession
+	}
+	err = checkCSRF(session, r)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// checkCSRF requires unsafe requests to carry the CSRF token of the session.
+func checkCSRF(session *models.Session, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	csrfToken := r.Header.Get(constants.CSRFHeader)
+	if csrfToken == "" || subtle.ConstantTimeCompare([]byte(HashToken(csrfToken)), []byte(session.CSRFHash)) != 1 {
+		return ErrInvalidCSRF
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func TestCheckCSRF(t *testing.T) {
+	csrfToken, csrfHash, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("cannot create csrf token: %v", err)
+	}
+	otherToken, _, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("cannot create csrf token: %v", err)
+	}
+	session := &models.Session{CSRFHash: csrfHash}
+	tests := []struct {
+		name    string
+		method  string
+		header  string
+		wantErr error
+	}{
+		{"get without token", "GET", "", nil},
+		{"head without token", "HEAD", "", nil},
+		{"options without token", "OPTIONS", "", nil},
+		{"post with token", "POST", csrfToken, nil},
+		{"delete with token", "DELETE", csrfToken, nil},
+		{"post without token", "POST", "", ErrInvalidCSRF},
+		{"put with another token", "PUT", otherToken, ErrInvalidCSRF},
+		{"patch with the hash instead of the token", "PATCH", csrfHash, ErrInvalidCSRF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/film/5", nil)
+			if tt.header != "" {
+				r.Header.Set(constants.CSRFHeader, tt.header)
+			}
+			if err := checkCSRF(session, r); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
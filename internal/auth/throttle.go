@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/db"
+)
+
+// LockedError is returned by Login while the account or the address is
+// locked after failed attempts.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "too many failed login attempts"
+}
+
+// RetryAfterSeconds is the value of the Retry-After header.
+func (e *LockedError) RetryAfterSeconds() string {
+	return fmt.Sprint(int(e.RetryAfter.Seconds()) + 1)
+}
+
+// ClientIP returns the address the request came from.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func AccountKey(name string) string {
+	return "user:" + name
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLocked returns LockedError if the account or the address is locked
+// and reports whether the account has failed logins to clear on success.
+func checkLocked(name string, ip string) (bool, error) {
+	attempts, err := db.Instance().GetLoginAttempts([]string{AccountKey(name), ipKey(ip)})
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, attempt := range attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			retryAfter = max(retryAfter, attempt.LockedUntil.Sub(now))
+		}
+	}
+	if retryAfter > 0 {
+		log.Printf("WARNING login attempt for %q from %v during lockout", name, ip)
+		return false, &LockedError{RetryAfter: retryAfter}
+	}
+	_, hasFailures := attempts[AccountKey(name)]
+	return hasFailures, nil
+}
+
+// recordFailure counts the failed login for the account and the address and
+// locks them when they reach the backoff or the lockout threshold.
+func recordFailure(name string, ip string) error {
+	err := addFailure(AccountKey(name), constants.AccountLockoutFailures, name, ip)
+	if err != nil {
+		return err
+	}
+	return addFailure(ipKey(ip), constants.IPLockoutFailures, name, ip)
+}
+
+func addFailure(key string, lockoutFailures int, name string, ip string) error {
+	now := time.Now()
+	failures, err := db.Instance().AddLoginFailure(key, now, now.Add(-constants.LoginFailureWindow))
+	if err != nil {
+		return err
+	}
+	if failures <= constants.LoginFreeAttempts {
+		return nil
+	}
+	lock := constants.LoginBackoffMax
+	if shift := failures - constants.LoginFreeAttempts - 1; shift < 16 {
+		lock = min(constants.LoginBackoffBase<<shift, constants.LoginBackoffMax)
+	}
+	if failures >= lockoutFailures {
+		lock = constants.LoginLockoutDuration
+		if failures == lockoutFailures {
+			log.Printf("WARNING %v locked for %v after %v failed logins, last one for %q from %v", key, lock, failures, name, ip)
+		}
+	}
+	return db.Instance().LockLogin(key, now.Add(lock))
+}
+
+// Unlock lifts the lock of the account and forgets its failed logins.
+func Unlock(name string) error {
+	_, err := db.Instance().ClearLoginAttempts(AccountKey(name))
+	return err
+}
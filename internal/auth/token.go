@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+var (
+	ErrNotConfigured = errors.New("bearer tokens are not configured")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrExpiredToken  = errors.New("token expired")
+)
+
+// Signer issues and verifies HS256 access tokens. Tokens are signed with the
+// active key and verified with any configured one, so a key can be rotated by
+// adding a new kid, making it active and removing the old kid once the tokens
+// signed with it have expired.
+type Signer struct {
+	keys      map[string][]byte
+	activeKid string
+	ttl       time.Duration
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Claims are the payload of the access token.
+type Claims struct {
+	UserID    int    `json:"uid"`
+	Name      string `json:"sub"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (c *Claims) User() *models.User {
+	return &models.User{ID: c.UserID, Name: c.Name, Role: c.Role}
+}
+
+var signer *Signer
+
+// Init configures the signer. Without keys bearer tokens are disabled and
+// only Basic auth is accepted.
+func Init(keys map[string][]byte, activeKid string, ttl time.Duration) error {
+	if len(keys) == 0 {
+		signer = nil
+		return nil
+	}
+	if _, ok := keys[activeKid]; !ok {
+		return fmt.Errorf("active key %q is not configured", activeKid)
+	}
+	signer = &Signer{keys: keys, activeKid: activeKid, ttl: ttl}
+	return nil
+}
+
+func Instance() *Signer {
+	return signer
+}
+
+// ParseKeys parses keys in the kid:secret,kid:secret format and returns them
+// along with the first kid.
+func ParseKeys(value string) (map[string][]byte, string, error) {
+	keys := map[string][]byte{}
+	first := ""
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" {
+			return nil, "", fmt.Errorf("key should be in kid:secret format")
+		}
+		if len(secret) < 32 {
+			return nil, "", fmt.Errorf("secret of key %q should be at least 32 characters", kid)
+		}
+		if _, ok := keys[kid]; ok {
+			return nil, "", fmt.Errorf("duplicate key %q", kid)
+		}
+		if first == "" {
+			first = kid
+		}
+		keys[kid] = []byte(secret)
+	}
+	return keys, first, nil
+}
+
+// Sign returns the access token for the user and its lifetime.
+func (s *Signer) Sign(user *models.User, now time.Time) (string, time.Duration, error) {
+	if s == nil {
+		return "", 0, ErrNotConfigured
+	}
+	headerJSON, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: s.activeKid})
+	if err != nil {
+		return "", 0, err
+	}
+	claimsJSON, err := json.Marshal(Claims{
+		UserID:    user.ID,
+		Name:      user.Name,
+		Role:      user.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	unsigned := encode(headerJSON) + "." + encode(claimsJSON)
+	return unsigned + "." + encode(sign(s.keys[s.activeKid], unsigned)), s.ttl, nil
+}
+
+// Verify checks the signature and the expiration of the token.
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	if s == nil {
+		return nil, ErrNotConfigured
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	headerJSON, err := decode(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	h := header{}
+	if err := json.Unmarshal(headerJSON, &h); err != nil || h.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+	key, ok := s.keys[h.Kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	signature, err := decode(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(key, parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+	claimsJSON, err := decode(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := Claims{}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil || claims.UserID < 1 {
+		return nil, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token and its hash. Only
+// the hash is stored.
+func NewRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := encode(buf)
+	return token, HashToken(token), nil
+}
+
+// NewPasswordResetToken returns a random single-use password reset token and
+// its hash.
+func NewPasswordResetToken() (string, string, error) {
+	return NewRefreshToken()
+}
+
+// HashToken returns the hash under which refresh tokens and API keys are
+// stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+const (
+	oldSecret = "old-secret-old-secret-old-secret"
+	newSecret = "new-secret-new-secret-new-secret"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantKeys  map[string][]byte
+		wantFirst string
+		wantErr   bool
+	}{
+		{"empty", "", map[string][]byte{}, "", false},
+		{"single key", "old:" + oldSecret, map[string][]byte{"old": []byte(oldSecret)}, "old", false},
+		{
+			"rotation keeps the first kid",
+			" new:" + newSecret + " , old:" + oldSecret + ",",
+			map[string][]byte{"new": []byte(newSecret), "old": []byte(oldSecret)},
+			"new",
+			false,
+		},
+		{"secret with colon", "old:" + oldSecret + ":x", map[string][]byte{"old": []byte(oldSecret + ":x")}, "old", false},
+		{"missing secret", "old", nil, "", true},
+		{"missing kid", ":" + oldSecret, nil, "", true},
+		{"short secret", "old:short", nil, "", true},
+		{"duplicate kid", "old:" + oldSecret + ",old:" + newSecret, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, first, err := ParseKeys(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("keys = %q, want %q", keys, tt.wantKeys)
+			}
+			if first != tt.wantFirst {
+				t.Errorf("first = %q, want %q", first, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestSignerVerify(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	user := &models.User{ID: 7, Name: "alice", Role: "admin"}
+	oldSigner := &Signer{keys: map[string][]byte{"old": []byte(oldSecret)}, activeKid: "old", ttl: time.Minute}
+	// The rotated signer signs with the new key and still accepts the old one.
+	rotated := &Signer{keys: map[string][]byte{"old": []byte(oldSecret), "new": []byte(newSecret)}, activeKid: "new", ttl: time.Minute}
+	// Once the old key is removed its tokens are rejected.
+	newOnly := &Signer{keys: map[string][]byte{"new": []byte(newSecret)}, activeKid: "new", ttl: time.Minute}
+
+	sign := func(s *Signer) string {
+		token, _, err := s.Sign(user, now)
+		if err != nil {
+			t.Fatalf("cannot sign token: %v", err)
+		}
+		return token
+	}
+	oldToken := sign(oldSigner)
+	newToken := sign(rotated)
+	parts := strings.Split(newToken, ".")
+	tamperedClaims := parts[0] + "." + encode([]byte(`{"uid":1,"sub":"root","role":"admin","exp":9999999999}`)) + "." + parts[2]
+	noneAlg := encode([]byte(`{"alg":"none","typ":"JWT","kid":"new"}`)) + "." + parts[1] + "." + parts[2]
+	unknownKid := encode([]byte(`{"alg":"HS256","typ":"JWT","kid":"other"}`)) + "." + parts[1] + "." + parts[2]
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", oldSigner, oldToken, now, nil},
+		{"old token after rotation", rotated, oldToken, now, nil},
+		{"new token after rotation", rotated, newToken, now, nil},
+		{"new token before rotation", oldSigner, newToken, now, ErrInvalidToken},
+		{"old token after the old key is removed", newOnly, oldToken, now, ErrInvalidToken},
+		{"expired", rotated, newToken, now.Add(time.Minute), ErrExpiredToken},
+		{"tampered claims", rotated, tamperedClaims, now, ErrInvalidToken},
+		{"none algorithm", rotated, noneAlg, now, ErrInvalidToken},
+		{"unknown kid", rotated, unknownKid, now, ErrInvalidToken},
+		{"malformed", rotated, "abc.def", now, ErrInvalidToken},
+		{"not configured", nil, newToken, now, ErrNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.Verify(tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if claims.UserID != user.ID || claims.Name != user.Name || claims.Role != user.Role {
+				t.Errorf("claims = %+v, want claims of %+v", claims, user)
+			}
+		})
+	}
+}
@@ -1,12 +1,46 @@
 package constants
 
-import "github.com/ffdb42/vk_trainee_task/internal/models"
+import (
+	"time"
 
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// Built-in roles. AdminRole can not be changed or deleted, ViewerRole is
+// given to users who sign up.
 const (
-	AdminRole = "admin"
-	UserRole  = "user"
+	AdminRole  = "admin"
+	ViewerRole = "viewer"
 )
 
+const (
+	PermissionFilmsRead         = "films:read"
+	PermissionFilmsCreate       = "films:create"
+	PermissionFilmsUpdate       = "films:update"
+	PermissionFilmsDelete       = "films:delete"
+	PermissionActorsRead        = "actors:read"
+	PermissionActorsCreate      = "actors:create"
+	PermissionActorsUpdate      = "actors:update"
+	PermissionActorsDelete      = "actors:delete"
+	PermissionActorsMerge       = "actors:merge"
+	PermissionFranchisesRead    = "franchises:read"
+	PermissionFranchisesWrite   = "franchises:write"
+	PermissionAwardsRead        = "awards:read"
+	PermissionAwardsWrite       = "awards:write"
+	PermissionRevisionsRevert   = "revisions:revert"
+	PermissionTrashManage       = "trash:manage"
+	PermissionAPIKeysManage     = "api_keys:manage"
+	PermissionRolesManage       = "roles:manage"
+	PermissionUsersManage       = "users:manage"
+	PermissionProposalsSubmit   = "proposals:submit"
+	PermissionProposalsModerate = "proposals:moderate"
+	PermissionMetricsRead       = "metrics:read"
+)
+
+// PermissionCacheTTL is how long permissions of roles and restricted
+// accounts are cached, changes made by other instances are picked up after it.
+const PermissionCacheTTL = time.Minute
+
 const (
 	SortDesc models.SortOrder = "DESC"
 	SortAsc  models.SortOrder = "ASC"
@@ -16,4 +50,180 @@ const (
 	SortByName        models.SortBy = "name"
 	SortByRating      models.SortBy = "rating"
 	SortByReleaseDate models.SortBy = "release_date"
+	SortByBoxOffice   models.SortBy = "box_office"
+)
+
+const (
+	RelationSequel  models.RelationType = "sequel"
+	RelationPrequel models.RelationType = "prequel"
+	RelationRemake  models.RelationType = "remake"
+	RelationSpinOff models.RelationType = "spin_off"
+)
+
+const (
+	DirectionOutgoing models.RelationDirection = "outgoing"
+	DirectionIncoming models.RelationDirection = "incoming"
+)
+
+const (
+	FranchiseOrderPosition    = "position"
+	FranchiseOrderReleaseDate = "release_date"
+)
+
+const (
+	ImageEntityFilm  models.ImageEntity = "film"
+	ImageEntityActor models.ImageEntity = "actor"
+)
+
+const (
+	TrashEntityFilm  models.TrashEntity = "film"
+	TrashEntityActor models.TrashEntity = "actor"
+)
+
+const (
+	MaxImageSize      = 10 << 20
+	MaxImageDimension = 8000
+	ImageFormField    = "image"
+)
+
+// ThumbnailSizes maps thumbnail names to the maximum length of the longest
+// side in pixels.
+var ThumbnailSizes = map[string]int{
+	"small":  160,
+	"medium": 480,
+	"large":  1080,
+}
+
+const DefaultLanguage = "ru"
+
+// CyrillicLanguages are languages for which actor names are not
+// transliterated when no translation is available.
+var CyrillicLanguages = map[string]bool{
+	"ru": true,
+	"uk": true,
+	"be": true,
+	"bg": true,
+	"sr": true,
+	"kk": true,
+	"mk": true,
+}
+
+const (
+	AliasStage           models.AliasType = "stage"
+	AliasBirth           models.AliasType = "birth"
+	AliasMaiden          models.AliasType = "maiden"
+	AliasMarried         models.AliasType = "married"
+	AliasTransliteration models.AliasType = "transliteration"
+	AliasOther           models.AliasType = "other"
+)
+
+// DuplicateScoreThreshold is the minimal score of an existing actor or film
+// to be reported as a likely duplicate of a created one.
+const DuplicateScoreThreshold = 0.8
+
+const (
+	ExternalSourceIMDb      models.ExternalSource = "imdb"
+	ExternalSourceKinopoisk models.ExternalSource = "kinopoisk"
+	ExternalSourceWikidata  models.ExternalSource = "wikidata"
 )
+
+// DefaultTrashRetentionDays is how long deleted films and actors are kept in
+// the trash unless TRASH_RETENTION_DAYS is set.
+const DefaultTrashRetentionDays = 30
+
+const TrashPurgeInterval = time.Hour
+
+const (
+	RevisionEntityFilm  models.RevisionEntity = "film"
+	RevisionEntityActor models.RevisionEntity = "actor"
+	RevisionEntityCast  models.RevisionEntity = "cast"
+)
+
+const (
+	RevisionCreate  models.RevisionAction = "create"
+	RevisionUpdate  models.RevisionAction = "update"
+	RevisionDelete  models.RevisionAction = "delete"
+	RevisionRestore models.RevisionAction = "restore"
+	RevisionRevert  models.RevisionAction = "revert"
+)
+
+const (
+	ProposalPending  models.ProposalStatus = "pending"
+	ProposalApproved models.ProposalStatus = "approved"
+	ProposalRejected models.ProposalStatus = "rejected"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// AuthPurgeInterval is how often expired refresh tokens and stale failed
+	// logins are removed.
+	AuthPurgeInterval = time.Hour
+)
+
+// Brute-force protection. After LoginFreeAttempts failures in a row every
+// next failure locks the login for twice as long, starting from
+// LoginBackoffBase up to LoginBackoffMax. Reaching the lockout number of
+// failures locks it for LoginLockoutDuration. Failures are forgotten after
+// LoginFailureWindow without new ones.
+const (
+	LoginFreeAttempts      = 3
+	LoginBackoffBase       = time.Second
+	LoginBackoffMax        = time.Minute
+	AccountLockoutFailures = 10
+	IPLockoutFailures      = 50
+	LoginLockoutDuration   = 15 * time.Minute
+	LoginFailureWindow     = time.Hour
+)
+
+const (
+	GrantTypePassword     = "password"
+	GrantTypeRefreshToken = "refresh_token"
+)
+
+// Password reset. A user can request a reset token once per
+// PasswordResetRequestInterval, the token is valid for PasswordResetTokenTTL.
+const (
+	PasswordResetTokenTTL        = time.Hour
+	PasswordResetRequestInterval = time.Minute
+)
+
+// Successful password verifications are cached for CredentialCacheTTL, at
+// most CredentialCacheSize of them.
+const (
+	CredentialCacheTTL  = time.Minute
+	CredentialCacheSize = 10000
+)
+
+// Browser sessions. A session ends after SessionIdleTimeout without requests
+// or SessionAbsoluteTimeout after the login, whichever comes first. Requests
+// other than GET, HEAD and OPTIONS made with the session cookie have to
+// repeat the CSRF token from CSRFCookieName in CSRFHeader.
+const (
+	SessionCookieName      = "session"
+	CSRFCookieName         = "csrf_token"
+	CSRFHeader             = "X-CSRF-Token"
+	SessionIdleTimeout     = 30 * time.Minute
+	SessionAbsoluteTimeout = 12 * time.Hour
+)
+
+// ChangePasswordPath is the only path available to users who have to reset
+// the password.
+const ChangePasswordPath = "/auth/password"
+
+// Scopes of API keys. Read scopes grant GET requests to the resource, write
+// scopes grant the rest.
+const (
+	ScopeFilmsRead       = "films:read"
+	ScopeFilmsWrite      = "films:write"
+	ScopeActorsRead      = "actors:read"
+	ScopeActorsWrite     = "actors:write"
+	ScopeFranchisesRead  = "franchises:read"
+	ScopeFranchisesWrite = "franchises:write"
+	ScopeAwardsRead      = "awards:read"
+	ScopeAwardsWrite     = "awards:write"
+	ScopeSearch          = "search"
+)
+
+// APIKeyHeader is the header API keys are passed in.
+const APIKeyHeader = "X-API-Key"
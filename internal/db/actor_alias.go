@@ -0,0 +1,134 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+// AddActorAlias adds the alias. If the alias is primary, the previous
+// primary alias of the actor stops being one.
+func (db *DBProvider) AddActorAlias(alias *models.ActorAlias) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return -1, err
+	}
+	defer tx.Rollback()
+	if alias.Primary {
+		err = resetPrimaryAlias(tx, alias.ActorID)
+		if err != nil {
+			return -1, err
+		}
+	}
+	id := 0
+	err = tx.QueryRow(
+		"INSERT INTO actors_aliases (actor_id, name, type, lang, is_primary) values ($1, $2, $3, $4, $5) RETURNING id;",
+		alias.ActorID,
+		alias.Name,
+		alias.Type,
+		alias.Lang,
+		alias.Primary,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, tx.Commit()
+}
+
+func (db *DBProvider) UpdateActorAlias(alias *models.ActorAlias) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if alias.Primary {
+		err = resetPrimaryAlias(tx, alias.ActorID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(
+		"UPDATE actors_aliases SET name = $1, type = $2, lang = $3, is_primary = $4 WHERE id = $5 AND actor_id = $6;",
+		alias.Name,
+		alias.Type,
+		alias.Lang,
+		alias.Primary,
+		alias.ID,
+		alias.ActorID,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func resetPrimaryAlias(tx querier, actorID int) error {
+	_, err := tx.Exec("UPDATE actors_aliases SET is_primary = FALSE WHERE actor_id = $1 AND is_primary;", actorID)
+	return err
+}
+
+func (db *DBProvider) DeleteActorAlias(actorID int, aliasID int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM actors_aliases WHERE id = $1 AND actor_id = $2;", aliasID, actorID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+func (db *DBProvider) GetActorAlias(actorID int, aliasID int) (*models.ActorAlias, error) {
+	rows, err := db.db.Query("SELECT * FROM actors_aliases WHERE id = $1 AND actor_id = $2;", aliasID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		res := models.ActorAlias{}
+		err := rows.Scan(&res.ID, &res.ActorID, &res.Name, &res.Type, &res.Lang, &res.Primary)
+		if err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, nil
+}
+
+func (db *DBProvider) GetActorAliases(actorID int) ([]*models.ActorAlias, error) {
+	rows, err := db.db.Query("SELECT * FROM actors_aliases WHERE actor_id = $1 ORDER BY is_primary DESC, name;", actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ActorAlias{}
+	for rows.Next() {
+		alias := models.ActorAlias{}
+		err := rows.Scan(&alias.ID, &alias.ActorID, &alias.Name, &alias.Type, &alias.Lang, &alias.Primary)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &alias)
+	}
+	return res, nil
+}
+
+// GetPrimaryAliases returns primary alias names by actor id.
+func (db *DBProvider) GetPrimaryAliases(actorIDs []int) (map[int]string, error) {
+	rows, err := db.db.Query("SELECT actor_id, name FROM actors_aliases WHERE is_primary AND actor_id = ANY($1);", pq.Array(actorIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[int]string{}
+	for rows.Next() {
+		var actorID int
+		var name string
+		err := rows.Scan(&actorID, &name)
+		if err != nil {
+			return nil, err
+		}
+		res[actorID] = name
+	}
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+func (db *DBProvider) GetActorDetails(actorID int) (*models.ActorDetails, error) {
+	rows, err := db.db.Query("SELECT death_date, birthplace, nationalities, biography FROM actors_details WHERE actor_id = $1;", actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	res := models.ActorDetails{}
+	var deathDate sql.NullTime
+	err = rows.Scan(&deathDate, &res.Birthplace, pq.Array(&res.Nationalities), &res.Biography)
+	if err != nil {
+		return nil, err
+	}
+	if deathDate.Valid {
+		res.DeathDate = &models.CustomDate{Time: deathDate.Time}
+	}
+	return &res, nil
+}
+
+// GetActorsDetails returns details of the listed actors that have them.
+func (db *DBProvider) GetActorsDetails(ids []int) (map[int]*models.ActorDetails, error) {
+	rows, err := db.db.Query("SELECT actor_id, death_date, birthplace, nationalities, biography FROM actors_details WHERE actor_id = ANY($1);", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[int]*models.ActorDetails{}
+	for rows.Next() {
+		var actorID int
+		details := models.ActorDetails{}
+		var deathDate sql.NullTime
+		err := rows.Scan(&actorID, &deathDate, &details.Birthplace, pq.Array(&details.Nationalities), &details.Biography)
+		if err != nil {
+			return nil, err
+		}
+		if deathDate.Valid {
+			details.DeathDate = &models.CustomDate{Time: deathDate.Time}
+		}
+		res[actorID] = &details
+	}
+	return res, nil
+}
+
+func (db *DBProvider) SetActorDetails(actorID int, details *models.ActorDetails) error {
+	var deathDate *time.Time
+	if details.DeathDate != nil {
+		deathDate = &details.DeathDate.Time
+	}
+	nationalities := details.Nationalities
+	if nationalities == nil {
+		nationalities = []string{}
+	}
+	_, err := db.db.Exec(
+		"INSERT INTO actors_details (actor_id, death_date, birthplace, nationalities, biography) values ($1, $2, $3, $4, $5) ON CONFLICT (actor_id) DO UPDATE SET death_date = EXCLUDED.death_date, birthplace = EXCLUDED.birthplace, nationalities = EXCLUDED.nationalities, biography = EXCLUDED.biography;",
+		actorID,
+		deathDate,
+		details.Birthplace,
+		pq.Array(nationalities),
+		details.Biography,
+	)
+	return err
+}
+
+// GetActorsDeathDates returns death dates of the listed actors that have one.
+func (db *DBProvider) GetActorsDeathDates(ids []int) (map[int]time.Time, error) {
+	rows, err := db.db.Query("SELECT actor_id, death_date FROM actors_details WHERE actor_id = ANY($1) AND death_date IS NOT NULL;", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[int]time.Time{}
+	for rows.Next() {
+		var actorID int
+		var deathDate time.Time
+		err := rows.Scan(&actorID, &deathDate)
+		if err != nil {
+			return nil, err
+		}
+		res[actorID] = deathDate
+	}
+	return res, nil
+}
+
+func (db *DBProvider) GetFilmPosthumousActors(filmID int) ([]int, error) {
+	rows, err := db.db.Query("SELECT actor_id FROM films_actors WHERE film_id = $1 AND posthumous ORDER BY actor_id;", filmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []int{}
+	for rows.Next() {
+		var actorID int
+		err := rows.Scan(&actorID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, actorID)
+	}
+	return res, nil
+}
+
+// SetFilmPosthumousActors flags credits of the listed actors as posthumous
+// and clears the flag of all other credits of the film.
+func (db *DBProvider) SetFilmPosthumousActors(filmID int, ids []int) error {
+	_, err := db.db.Exec("UPDATE films_actors SET posthumous = (actor_id = ANY($2)) WHERE film_id = $1;", filmID, pq.Array(ids))
+	return err
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// MergeActors moves credits, nominations, aliases, translations, biography,
+// external ids and photo of the duplicate actor to the survivor, keeps the duplicate's
+// name as an alias, deletes the duplicate and records a redirect from its id.
+// Conflicting records of the survivor win. Returns ErrNotFound if either actor
+// does not exist and the duplicate's photo if it was dropped, so that its
+// blobs can be removed.
+func (db *DBProvider) MergeActors(survivorID int, duplicateID int) (*models.Image, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var firstName, lastName sql.NullString
+	found := 0
+	rows, err := tx.Query("SELECT id, first_name, last_name FROM actors WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE;", survivorID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var first, last sql.NullString
+		err = rows.Scan(&id, &first, &last)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if id == duplicateID {
+			firstName, lastName = first, last
+		}
+		found++
+	}
+	rows.Close()
+	if found != 2 {
+		return nil, ErrNotFound
+	}
+
+	statements := []string{
+		// Nominations of the duplicate that the survivor also has are merged
+		// into the survivor's ones.
+		"UPDATE nominations s SET won = s.won OR d.won FROM nominations d WHERE s.actor_id = $1 AND d.actor_id = $2 AND s.award_id = d.award_id AND s.film_id = d.film_id;",
+		"DELETE FROM nominations d USING nominations s WHERE s.actor_id = $1 AND d.actor_id = $2 AND s.award_id = d.award_id AND s.film_id = d.film_id;",
+		"UPDATE films_actors s SET posthumous = s.posthumous OR d.posthumous FROM films_actors d WHERE s.actor_id = $1 AND d.actor_id = $2 AND s.film_id = d.film_id;",
+		// The survivor is credited in every film of the duplicate before the
+		// nominations are moved, since they reference the credits.
+		"INSERT INTO films_actors (film_id, actor_id, posthumous) SELECT film_id, $1, posthumous FROM films_actors WHERE actor_id = $2 ON CONFLICT (film_id, actor_id) DO NOTHING;",
+		"UPDATE nominations SET actor_id = $1 WHERE actor_id = $2;",
+		"DELETE FROM films_actors WHERE actor_id = $2;",
+		"DELETE FROM actors_aliases d USING actors_aliases s WHERE s.actor_id = $1 AND d.actor_id = $2 AND s.name = d.name;",
+		"UPDATE actors_aliases SET actor_id = $1, is_primary = FALSE WHERE actor_id = $2;",
+		"UPDATE actors_translations SET actor_id = $1 WHERE actor_id = $2 AND lang NOT IN (SELECT lang FROM actors_translations WHERE actor_id = $1);",
+		"UPDATE actors_details SET actor_id = $1 WHERE actor_id = $2 AND NOT EXISTS (SELECT 1 FROM actors_details WHERE actor_id = $1);",
+		"UPDATE actors_redirects SET actor_id = $1 WHERE actor_id = $2;",
+		"UPDATE actors_external_ids SET actor_id = $1 WHERE actor_id = $2;",
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement, survivorID, duplicateID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	name := strings.TrimSpace(firstName.String + " " + lastName.String)
+	if name != "" {
+		_, err = tx.Exec(
+			"INSERT INTO actors_aliases (actor_id, name, type) SELECT $1, $2, $3 FROM actors WHERE id = $1 AND CONCAT(first_name, ' ', last_name) <> $2 ON CONFLICT (actor_id, name) DO NOTHING;",
+			survivorID,
+			name,
+			constants.AliasOther,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dropped, err := mergeActorImage(tx, survivorID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM actors WHERE id = $1;", duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec("INSERT INTO actors_redirects (old_id, actor_id) values ($1, $2);", duplicateID, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	return dropped, tx.Commit()
+}
+
+// mergeActorImage gives the duplicate's photo to the survivor if it has none,
+// otherwise drops the record and returns it.
+func mergeActorImage(tx querier, survivorID int, duplicateID int) (*models.Image, error) {
+	res, err := tx.Exec(
+		"UPDATE images SET entity_id = $1 WHERE entity = $3 AND entity_id = $2 AND NOT EXISTS (SELECT 1 FROM images WHERE entity = $3 AND entity_id = $1);",
+		survivorID,
+		duplicateID,
+		constants.ImageEntityActor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil || moved > 0 {
+		return nil, err
+	}
+	rows, err := tx.Query("DELETE FROM images WHERE entity = $1 AND entity_id = $2 RETURNING *;", constants.ImageEntityActor, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	image := models.Image{}
+	err = rows.Scan(&image.ID, &image.Entity, &image.EntityID, &image.KeyPrefix, &image.ContentType, &image.Width, &image.Height, &image.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// GetActorRedirect returns the id of the actor the merged one was redirected
+// to, or -1 if there is no redirect.
+func (db *DBProvider) GetActorRedirect(oldID int) (int, error) {
+	rows, err := db.db.Query("SELECT actor_id FROM actors_redirects WHERE old_id = $1;", oldID)
+	if err != nil {
+		return -1, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return -1, nil
+	}
+	id := 0
+	err = rows.Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = "api_keys.id, users.name, api_keys.name, api_keys.prefix, api_keys.scopes, api_keys.allowed_ips, api_keys.created_by, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.revoked_at, users.id, users.role"
+
+func (db *DBProvider) AddAPIKey(key *models.APIKey, keyHash string) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, allowed_ips, created_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;",
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		keyHash,
+		pq.Array(key.Scopes),
+		pq.Array(key.AllowedIPs),
+		key.CreatedBy,
+		key.ExpiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *DBProvider) GetAPIKey(id int) (*models.APIKey, error) {
+	keys, err := db.getAPIKeys("SELECT "+apiKeyColumns+" FROM api_keys JOIN users ON users.id = api_keys.user_id WHERE api_keys.id = $1;", id)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// GetAPIKeys returns keys of the user, or keys of all users if userID is 0.
+func (db *DBProvider) GetAPIKeys(userID int) ([]*models.APIKey, error) {
+	return db.getAPIKeys("SELECT "+apiKeyColumns+" FROM api_keys JOIN users ON users.id = api_keys.user_id WHERE $1 = 0 OR api_keys.user_id = $1 ORDER BY api_keys.id;", userID)
+}
+
+// UseAPIKey returns the key with the given hash and marks it as used, nil
+// means that there is no such key. Revoked and expired keys are returned too,
+// the caller decides how to report them.
+func (db *DBProvider) UseAPIKey(keyHash string) (*models.APIKey, error) {
+	keys, err := db.getAPIKeys("UPDATE api_keys SET last_used_at = NOW() FROM users WHERE users.id = api_keys.user_id AND api_keys.key_hash = $1 AND api_keys.revoked_at IS NULL RETURNING "+apiKeyColumns+";", keyHash)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// RotateAPIKey replaces the secret of an active key, the old secret stops
+// working immediately.
+func (db *DBProvider) RotateAPIKey(id int, prefix, keyHash string) error {
+	n, err := db.execCount("UPDATE api_keys SET prefix = $2, key_hash = $3 WHERE id = $1 AND revoked_at IS NULL;", id, prefix, keyHash)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (db *DBProvider) RevokeAPIKey(id int) (int64, error) {
+	return db.execCount("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL;", id)
+}
+
+func (db *DBProvider) getAPIKeys(query string, args ...any) ([]*models.APIKey, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.APIKey{}
+	for rows.Next() {
+		key := models.APIKey{}
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		err := rows.Scan(
+			&key.ID,
+			&key.Login,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&key.Scopes),
+			pq.Array(&key.AllowedIPs),
+			&key.CreatedBy,
+			&key.CreatedAt,
+			&expiresAt,
+			&lastUsedAt,
+			&revokedAt,
+			&key.UserID,
+			&key.Role,
+		)
+		if err != nil {
+			return nil, err
+		}
+		key.ExpiresAt = nullTime(expiresAt)
+		key.LastUsedAt = nullTime(lastUsedAt)
+		key.RevokedAt = nullTime(revokedAt)
+		res = append(res, &key)
+	}
+	return res, nil
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func (db *DBProvider) AddAward(award *models.Award) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO awards (ceremony, year, category) values ($1, $2, $3) RETURNING id;",
+		award.Ceremony,
+		award.Year,
+		award.Category,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (db *DBProvider) UpdateAward(award *models.Award) error {
+	_, err := db.db.Exec(
+		"UPDATE awards SET ceremony = $1, year = $2, category = $3 WHERE id = $4;",
+		award.Ceremony,
+		award.Year,
+		award.Category,
+		award.ID,
+	)
+	return err
+}
+
+func (db *DBProvider) GetAward(id int) (*models.Award, error) {
+	rows, err := db.db.Query("SELECT * FROM awards WHERE id = $1;", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		res := models.Award{}
+		err := rows.Scan(&res.ID, &res.Ceremony, &res.Year, &res.Category)
+		if err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, nil
+}
+
+// GetAwards returns awards ordered by ceremony, year and category. Empty
+// ceremony or zero year means no filtering.
+func (db *DBProvider) GetAwards(ceremony string, year int) ([]*models.Award, error) {
+	rows, err := db.db.Query(
+		"SELECT * FROM awards WHERE ($1 = '' OR ceremony = $1) AND ($2 = 0 OR year = $2) ORDER BY ceremony, year DESC, category;",
+		ceremony,
+		year,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Award{}
+	for rows.Next() {
+		award := models.Award{}
+		err := rows.Scan(&award.ID, &award.Ceremony, &award.Year, &award.Category)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &award)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) DeleteAward(id int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM awards WHERE id = $1;", id)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+func (db *DBProvider) GetCeremonies() ([]*models.CeremonyRespond, error) {
+	rows, err := db.db.Query("SELECT DISTINCT ceremony, year FROM awards ORDER BY ceremony, year DESC;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.CeremonyRespond{}
+	for rows.Next() {
+		var ceremony string
+		var year int
+		err := rows.Scan(&ceremony, &year)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 || res[len(res)-1].Ceremony != ceremony {
+			res = append(res, &models.CeremonyRespond{Ceremony: ceremony, Years: []int{}})
+		}
+		res[len(res)-1].Years = append(res[len(res)-1].Years, year)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) AddNomination(nomination *models.Nomination) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO nominations (award_id, film_id, actor_id, won) values ($1, $2, $3, $4) RETURNING id;",
+		nomination.AwardID,
+		nomination.FilmID,
+		nomination.ActorID,
+		nomination.Won,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (db *DBProvider) UpdateNomination(awardID int, nominationID int, won bool) (int64, error) {
+	res, err := db.db.Exec("UPDATE nominations SET won = $1 WHERE id = $2 AND award_id = $3;", won, nominationID, awardID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+func (db *DBProvider) DeleteNomination(awardID int, nominationID int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM nominations WHERE id = $1 AND award_id = $2;", nominationID, awardID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// activeNominations leaves out nominations of films and actors in the trash.
+const activeNominations = " AND nominations.film_id IN (SELECT id FROM films WHERE deleted_at IS NULL) AND (nominations.actor_id IS NULL OR nominations.actor_id IN (SELECT id FROM actors WHERE deleted_at IS NULL))"
+
+func (db *DBProvider) GetAwardNominations(awardID int) ([]*models.Nomination, error) {
+	return db.getNominations("SELECT * FROM nominations WHERE award_id = $1"+activeNominations+" ORDER BY won DESC, id;", awardID)
+}
+
+func (db *DBProvider) GetFilmNominations(filmID int) ([]*models.Nomination, error) {
+	return db.getNominations("SELECT nominations.* FROM nominations JOIN awards ON awards.id = nominations.award_id WHERE nominations.film_id = $1"+activeNominations+" ORDER BY awards.year DESC, awards.ceremony, awards.category;", filmID)
+}
+
+func (db *DBProvider) GetActorNominations(actorID int) ([]*models.Nomination, error) {
+	return db.getNominations("SELECT nominations.* FROM nominations JOIN awards ON awards.id = nominations.award_id WHERE nominations.actor_id = $1"+activeNominations+" ORDER BY awards.year DESC, awards.ceremony, awards.category;", actorID)
+}
+
+func (db *DBProvider) getNominations(query string, id int) ([]*models.Nomination, error) {
+	rows, err := db.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Nomination{}
+	for rows.Next() {
+		nomination := models.Nomination{}
+		err := rows.Scan(&nomination.ID, &nomination.AwardID, &nomination.FilmID, &nomination.ActorID, &nomination.Won)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &nomination)
+	}
+	return res, nil
+}
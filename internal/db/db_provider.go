@@ -5,17 +5,46 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
 	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/ffdb42/vk_trainee_task/internal/utils"
 	_ "github.com/lib/pq"
 )
 
+// DBProvider runs queries on the connection pool, or inside a transaction if
+// it was obtained from InTx.
 type DBProvider struct {
-	db *sql.DB
+	db   querier
+	pool *sql.DB
+	tx   *sql.Tx
+}
+
+// querier is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 var instance *DBProvider
 
+// Columns scanned into models.Film, models.Actor and models.User, soft
+// deletion columns are left out.
+const (
+	filmColumns  = "films.id, films.name, films.description, films.release_date, films.rating"
+	actorColumns = "actors.id, actors.first_name, actors.last_name, actors.sex, actors.birthdate"
+	userColumns  = "users.id, users.name, users.role, users.password, users.disabled_at IS NOT NULL, users.must_reset_password, users.credentials_changed_at"
+)
+
+var sortColumns = map[models.SortBy]string{
+	constants.SortByName:        "films.name",
+	constants.SortByRating:      "films.rating",
+	constants.SortByReleaseDate: "films.release_date",
+	constants.SortByBoxOffice:   "films_details.box_office",
+}
+
 func Init() {
 	user := os.Getenv("POSTGRES_USER")
 	pass := os.Getenv("POSTGRES_PASSWORD")
@@ -33,7 +62,11 @@ func Init() {
 		log.Fatalf("cannot ping db: %v", err)
 	}
 
-	instance = &DBProvider{db: db}
+	instance = &DBProvider{db: db, pool: db}
+
+	if err = instance.migrate(); err != nil {
+		log.Fatalf("cannot migrate db: %v", err)
+	}
 
 	log.Printf("established connection to db")
 }
@@ -42,23 +75,27 @@ func Instance() *DBProvider {
 	return instance
 }
 
-func (db *DBProvider) AddActor(actor *models.Actor) error {
-	_, err := db.db.Exec(
-		"INSERT INTO actors (first_name, last_name, sex, birthdate) values ($1, $2, $3, $4);",
+func (db *DBProvider) AddActor(actor *models.Actor) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO actors (first_name, last_name, sex, birthdate) values ($1, $2, $3, $4) RETURNING id;",
 		actor.FirstName,
 		actor.LastName,
 		actor.Sex,
 		actor.Birthdate.Time,
-	)
-	return err
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
 }
 
 func (db *DBProvider) UpdateActor(actor *models.Actor) error {
 	_, err := db.db.Exec(
-		"UPDATE actors SET first_name = $1, last_name = $2, sex = $3, birthdate = $4 WHERE id = $5;",
-		*actor.FirstName,
-		*actor.LastName,
-		*actor.Sex,
+		"UPDATE actors SET first_name = $1, last_name = $2, sex = $3, birthdate = $4 WHERE id = $5 AND deleted_at IS NULL;",
+		actor.FirstName,
+		actor.LastName,
+		actor.Sex,
 		actor.Birthdate.Time,
 		actor.ID,
 	)
@@ -66,7 +103,7 @@ func (db *DBProvider) UpdateActor(actor *models.Actor) error {
 }
 
 func (db *DBProvider) GetActor(id int) (*models.Actor, error) {
-	rows, err := db.db.Query("SELECT * FROM actors WHERE id = $1;", id)
+	rows, err := db.db.Query("SELECT "+actorColumns+" FROM actors WHERE id = $1 AND deleted_at IS NULL;", id)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +119,7 @@ func (db *DBProvider) GetActor(id int) (*models.Actor, error) {
 }
 
 func (db *DBProvider) GetActors() (*[]models.Actor, error) {
-	rows, err := db.db.Query("SELECT * FROM actors;")
+	rows, err := db.db.Query("SELECT " + actorColumns + " FROM actors WHERE deleted_at IS NULL;")
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +136,10 @@ func (db *DBProvider) GetActors() (*[]models.Actor, error) {
 	return &res, nil
 }
 
-func (db *DBProvider) DeleteActor(id int) (int64, error) {
-	res, err := db.db.Exec("DELETE FROM actors WHERE id = $1;", id)
+// DeleteActor moves the actor to the trash. Credits of the actor are kept so
+// that they come back on restore.
+func (db *DBProvider) DeleteActor(id int, deletedBy string) (int64, error) {
+	res, err := db.db.Exec("UPDATE actors SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL;", id, deletedBy)
 	if err != nil {
 		return -1, err
 	}
@@ -128,18 +167,18 @@ func (db *DBProvider) AddFilm(film *models.Film) (int, error) {
 
 func (db *DBProvider) UpdateFilm(film *models.Film) error {
 	_, err := db.db.Exec(
-		"UPDATE films SET name = $1, description = $2, release_date = $3, rating = $4 WHERE id = $5;",
+		"UPDATE films SET name = $1, description = $2, release_date = $3, rating = $4 WHERE id = $5 AND deleted_at IS NULL;",
 		film.Name,
-		*film.Description,
+		film.Description,
 		film.ReleaseDate.Time,
-		*film.Rating,
+		film.Rating,
 		film.ID,
 	)
 	return err
 }
 
 func (db *DBProvider) GetFilm(id int) (*models.Film, error) {
-	rows, err := db.db.Query("SELECT * FROM films WHERE id = $1;", id)
+	rows, err := db.db.Query("SELECT "+filmColumns+" FROM films WHERE id = $1 AND deleted_at IS NULL;", id)
 	if err != nil {
 		return nil, err
 	}
@@ -154,8 +193,26 @@ func (db *DBProvider) GetFilm(id int) (*models.Film, error) {
 	return &res, nil
 }
 
-func (db *DBProvider) GetFilms(sortBy models.SortBy, sortOrder models.SortOrder) (*[]models.Film, error) {
-	rows, err := db.db.Query(fmt.Sprintf("SELECT * FROM films ORDER BY %s %s;", sortBy, sortOrder))
+func (db *DBProvider) GetFilms(sortBy models.SortBy, sortOrder models.SortOrder, filter *models.FilmsFilter) (*[]models.Film, error) {
+	conditions := []string{"films.deleted_at IS NULL"}
+	args := []any{}
+	if filter.AwardWonID > 0 {
+		args = append(args, filter.AwardWonID)
+		conditions = append(conditions, fmt.Sprintf("films.id IN (SELECT film_id FROM nominations WHERE won AND award_id = $%d)", len(args)))
+	}
+	if filter.RuntimeMin > 0 {
+		args = append(args, filter.RuntimeMin)
+		conditions = append(conditions, fmt.Sprintf("films_details.runtime >= $%d", len(args)))
+	}
+	if filter.RuntimeMax > 0 {
+		args = append(args, filter.RuntimeMax)
+		conditions = append(conditions, fmt.Sprintf("films_details.runtime <= $%d", len(args)))
+	}
+	if filter.ReleasedIn != "" {
+		args = append(args, filter.ReleasedIn)
+		conditions = append(conditions, fmt.Sprintf("films.id IN (SELECT film_id FROM films_releases WHERE country = $%d)", len(args)))
+	}
+	rows, err := db.db.Query(fmt.Sprintf("SELECT %s FROM films LEFT JOIN films_details ON films_details.film_id = films.id WHERE %s ORDER BY %s %s NULLS LAST;", filmColumns, strings.Join(conditions, " AND "), sortColumns[sortBy], sortOrder), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -172,8 +229,10 @@ func (db *DBProvider) GetFilms(sortBy models.SortBy, sortOrder models.SortOrder)
 	return &res, nil
 }
 
-func (db *DBProvider) DeleteFilm(id int) (int64, error) {
-	res, err := db.db.Exec("DELETE FROM films WHERE id = $1;", id)
+// DeleteFilm moves the film to the trash. Cast of the film is kept so that it
+// comes back on restore.
+func (db *DBProvider) DeleteFilm(id int, deletedBy string) (int64, error) {
+	res, err := db.db.Exec("UPDATE films SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL;", id, deletedBy)
 	if err != nil {
 		return -1, err
 	}
@@ -184,18 +243,19 @@ func (db *DBProvider) DeleteFilm(id int) (int64, error) {
 	return count, nil
 }
 
-func (db *DBProvider) AddFilmsActors(actorID int, filmID int) error {
-	_, err := db.db.Exec(
-		"INSERT INTO films_actors (film_id, actor_id) values ($1, $2);",
+// AddFilmsActors credits the actor in the film. It reports false if the actor
+// was already credited.
+func (db *DBProvider) AddFilmsActors(actorID int, filmID int) (bool, error) {
+	n, err := db.execCount(
+		"INSERT INTO films_actors (film_id, actor_id) values ($1, $2) ON CONFLICT DO NOTHING;",
 		filmID,
 		actorID,
 	)
-	return err
+	return n > 0, err
 }
 
-func (db *DBProvider) DeleteFilmsActors(filmID int, actorID int) error {
-	_, err := db.db.Exec("DELETE FROM films_actors WHERE film_id = $1 AND actor_id = $2;", filmID, actorID)
-	return err
+func (db *DBProvider) DeleteFilmsActors(filmID int, actorID int) (int64, error) {
+	return db.execCount("DELETE FROM films_actors WHERE film_id = $1 AND actor_id = $2;", filmID, actorID)
 }
 
 func (db *DBProvider) AddUser(user *models.User) error {
@@ -209,32 +269,46 @@ func (db *DBProvider) AddUser(user *models.User) error {
 }
 
 func (db *DBProvider) GetUser(name string) (*models.User, error) {
-	rows, err := db.db.Query("SELECT * FROM users WHERE name = $1;", name)
+	return db.getUser("SELECT "+userColumns+" FROM users WHERE name = $1;", name)
+}
+
+func (db *DBProvider) GetUserByID(id int) (*models.User, error) {
+	return db.getUser("SELECT "+userColumns+" FROM users WHERE id = $1;", id)
+}
+
+func (db *DBProvider) getUser(query string, args ...any) (*models.User, error) {
+	rows, err := db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	res := models.User{}
 	if rows.Next() {
-		err := rows.Scan(&res.ID, &res.Name, &res.Role, &res.Password)
-		if err != nil {
-			return nil, err
-		}
-		return &res, nil
+		return scanUser(rows)
 	}
 	return nil, nil
 }
 
-func (db *DBProvider) GetActorFilms(actorID int) ([]*models.Film, error) {
-	rows, err := db.db.Query("SELECT films.* FROM films JOIN films_actors ON films.id = films_actors.film_id JOIN actors ON films_actors.actor_id = actors.id WHERE actors.id = $1", actorID)
+func scanUser(rows *sql.Rows) (*models.User, error) {
+	user := models.User{}
+	var credentialsChangedAt sql.NullTime
+	err := rows.Scan(&user.ID, &user.Name, &user.Role, &user.Password, &user.Disabled, &user.MustResetPassword, &credentialsChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	user.CredentialsChangedAt = nullTime(credentialsChangedAt)
+	return &user, nil
+}
+
+func (db *DBProvider) GetActorFilms(actorID int) ([]*models.ActorFilm, error) {
+	rows, err := db.db.Query("SELECT "+filmColumns+", films_actors.posthumous FROM films JOIN films_actors ON films.id = films_actors.film_id WHERE films_actors.actor_id = $1 AND films.deleted_at IS NULL;", actorID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	res := []*models.Film{}
+	res := []*models.ActorFilm{}
 	for rows.Next() {
-		film := models.Film{ReleaseDate: &models.CustomDate{}}
-		err := rows.Scan(&film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating)
+		film := models.ActorFilm{Film: &models.Film{ReleaseDate: &models.CustomDate{}}}
+		err := rows.Scan(&film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating, &film.Posthumous)
 		if err != nil {
 			return nil, err
 		}
@@ -244,7 +318,7 @@ func (db *DBProvider) GetActorFilms(actorID int) ([]*models.Film, error) {
 }
 
 func (db *DBProvider) GetFilmActors(filmID int) ([]*models.Actor, error) {
-	rows, err := db.db.Query("SELECT actors.* FROM actors JOIN films_actors ON actors.id = films_actors.actor_id JOIN films ON films_actors.film_id = films.id WHERE films.id =$1", filmID)
+	rows, err := db.db.Query("SELECT "+actorColumns+" FROM actors JOIN films_actors ON actors.id = films_actors.actor_id WHERE films_actors.film_id = $1 AND actors.deleted_at IS NULL;", filmID)
 	if err != nil {
 		return nil, err
 	}
@@ -261,8 +335,26 @@ func (db *DBProvider) GetFilmActors(filmID int) ([]*models.Actor, error) {
 	return res, nil
 }
 
+// SearchForFilmByStringFragment matches the fragment against film names,
+// actors' first names and aliases in every language, including their
+// transliteration.
 func (db *DBProvider) SearchForFilmByStringFragment(fragment string) ([]*models.Film, error) {
-	rows, err := db.db.Query("SELECT films.* FROM films	JOIN films_actors ON films.id = films_actors.film_id JOIN actors ON films_actors.actor_id = actors.id WHERE LOWER(films.name) LIKE '%' || $1 || '%' OR LOWER(actors.first_name) LIKE '%' || $1 || '%';", fragment)
+	rows, err := db.db.Query(
+		`SELECT `+filmColumns+` FROM films WHERE films.deleted_at IS NULL AND (
+			LOWER(films.name) LIKE '%' || LOWER($1) || '%'
+			OR EXISTS (SELECT 1 FROM films_translations WHERE films_translations.film_id = films.id AND LOWER(films_translations.name) LIKE '%' || LOWER($1) || '%')
+			OR EXISTS (SELECT 1 FROM films_actors JOIN actors ON films_actors.actor_id = actors.id LEFT JOIN actors_translations ON actors_translations.actor_id = actors.id
+				WHERE films_actors.film_id = films.id AND actors.deleted_at IS NULL AND (
+					LOWER(actors.first_name) LIKE '%' || LOWER($1) || '%'
+					OR LOWER(actors_translations.first_name) LIKE '%' || LOWER($1) || '%'
+					OR LOWER(actors_translations.first_name) LIKE '%' || LOWER($2) || '%'))
+			OR EXISTS (SELECT 1 FROM films_actors JOIN actors ON films_actors.actor_id = actors.id JOIN actors_aliases ON films_actors.actor_id = actors_aliases.actor_id
+				WHERE films_actors.film_id = films.id AND actors.deleted_at IS NULL AND (
+					LOWER(actors_aliases.name) LIKE '%' || LOWER($1) || '%'
+					OR LOWER(actors_aliases.name) LIKE '%' || LOWER($2) || '%')));`,
+		fragment,
+		utils.Transliterate(fragment),
+	)
 	if err != nil {
 		return nil, err
 	}
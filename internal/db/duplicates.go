@@ -0,0 +1,80 @@
+package db
+
+import (
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+// GetActorsBornOn returns actors born on the date, the only actors that can
+// be duplicates of an actor with this birthdate.
+func (db *DBProvider) GetActorsBornOn(birthdate time.Time) ([]*models.Actor, error) {
+	rows, err := db.db.Query("SELECT "+actorColumns+" FROM actors WHERE birthdate = $1 AND deleted_at IS NULL;", birthdate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Actor{}
+	for rows.Next() {
+		actor := models.Actor{Birthdate: &models.CustomDate{}}
+		err := rows.Scan(&actor.ID, &actor.FirstName, &actor.LastName, &actor.Sex, &actor.Birthdate.Time)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &actor)
+	}
+	return res, nil
+}
+
+// GetFilmsReleasedIn returns films released from the first year to the last
+// one inclusive.
+func (db *DBProvider) GetFilmsReleasedIn(firstYear int, lastYear int) ([]*models.Film, error) {
+	rows, err := db.db.Query("SELECT "+filmColumns+" FROM films WHERE EXTRACT(YEAR FROM release_date) BETWEEN $1 AND $2 AND deleted_at IS NULL;", firstYear, lastYear)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Film{}
+	for rows.Next() {
+		film := models.Film{ReleaseDate: &models.CustomDate{}}
+		err := rows.Scan(&film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &film)
+	}
+	return res, nil
+}
+
+// GetActorsOtherNames returns translated names and aliases of the actors.
+func (db *DBProvider) GetActorsOtherNames(actorIDs []int) (map[int][]string, error) {
+	return db.otherNames("SELECT actor_id, CONCAT_WS(' ', first_name, last_name) FROM actors_translations WHERE actor_id = ANY($1) UNION ALL SELECT actor_id, name FROM actors_aliases WHERE actor_id = ANY($1);", actorIDs)
+}
+
+// GetFilmsOtherNames returns translated names of the films.
+func (db *DBProvider) GetFilmsOtherNames(filmIDs []int) (map[int][]string, error) {
+	return db.otherNames("SELECT film_id, name FROM films_translations WHERE film_id = ANY($1) AND name IS NOT NULL;", filmIDs)
+}
+
+func (db *DBProvider) otherNames(query string, ids []int) (map[int][]string, error) {
+	res := map[int][]string{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	rows, err := db.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return nil, err
+		}
+		res[id] = append(res[id], name)
+	}
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func (db *DBProvider) GetFilmExternalIDs(filmID int) ([]*models.ExternalID, error) {
+	return db.getExternalIDs("films_external_ids", "film_id", filmID)
+}
+
+// SetFilmExternalIDs replaces all external ids of the film.
+func (db *DBProvider) SetFilmExternalIDs(filmID int, ids []*models.ExternalID) error {
+	return db.setExternalIDs("films_external_ids", "film_id", filmID, ids)
+}
+
+// GetFilmByExternalID returns the id of the film with the external id, or -1
+// if there is none.
+func (db *DBProvider) GetFilmByExternalID(source models.ExternalSource, value string) (int, error) {
+	return db.getByExternalID("films_external_ids", "film_id", "films", source, value)
+}
+
+func (db *DBProvider) GetActorExternalIDs(actorID int) ([]*models.ExternalID, error) {
+	return db.getExternalIDs("actors_external_ids", "actor_id", actorID)
+}
+
+// SetActorExternalIDs replaces all external ids of the actor.
+func (db *DBProvider) SetActorExternalIDs(actorID int, ids []*models.ExternalID) error {
+	return db.setExternalIDs("actors_external_ids", "actor_id", actorID, ids)
+}
+
+// GetActorByExternalID returns the id of the actor with the external id, or
+// -1 if there is none.
+func (db *DBProvider) GetActorByExternalID(source models.ExternalSource, value string) (int, error) {
+	return db.getByExternalID("actors_external_ids", "actor_id", "actors", source, value)
+}
+
+func (db *DBProvider) getExternalIDs(table string, column string, id int) ([]*models.ExternalID, error) {
+	rows, err := db.db.Query(fmt.Sprintf("SELECT source, value FROM %s WHERE %s = $1 ORDER BY source, value;", table, column), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ExternalID{}
+	for rows.Next() {
+		externalID := models.ExternalID{}
+		err := rows.Scan(&externalID.Source, &externalID.Value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &externalID)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) setExternalIDs(table string, column string, id int, ids []*models.ExternalID) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = $1;", table, column), id)
+	if err != nil {
+		return err
+	}
+	for _, externalID := range ids {
+		_, err = tx.Exec(
+			fmt.Sprintf("INSERT INTO %s (%s, source, value) values ($1, $2, $3);", table, column),
+			id,
+			externalID.Source,
+			externalID.Value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *DBProvider) getByExternalID(table string, column string, entityTable string, source models.ExternalSource, value string) (int, error) {
+	rows, err := db.db.Query(fmt.Sprintf("SELECT %[1]s FROM %[2]s WHERE source = $1 AND value = $2 AND %[1]s IN (SELECT id FROM %[3]s WHERE deleted_at IS NULL);", column, table, entityTable), source, value)
+	if err != nil {
+		return -1, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return -1, nil
+	}
+	id := 0
+	err = rows.Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
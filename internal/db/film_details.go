@@ -0,0 +1,95 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func (db *DBProvider) GetFilmDetails(filmID int) (*models.FilmDetails, error) {
+	rows, err := db.db.Query("SELECT runtime, budget, budget_currency, box_office, box_office_currency FROM films_details WHERE film_id = $1;", filmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	res := models.FilmDetails{}
+	var budget, boxOffice *int64
+	var budgetCurrency, boxOfficeCurrency *string
+	err = rows.Scan(&res.Runtime, &budget, &budgetCurrency, &boxOffice, &boxOfficeCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil && budgetCurrency != nil {
+		res.Budget = &models.Money{Amount: *budget, Currency: *budgetCurrency}
+	}
+	if boxOffice != nil && boxOfficeCurrency != nil {
+		res.BoxOffice = &models.Money{Amount: *boxOffice, Currency: *boxOfficeCurrency}
+	}
+	return &res, nil
+}
+
+func (db *DBProvider) SetFilmDetails(filmID int, details *models.FilmDetails) error {
+	var budget, boxOffice *int64
+	var budgetCurrency, boxOfficeCurrency *string
+	if details.Budget != nil {
+		budget, budgetCurrency = &details.Budget.Amount, &details.Budget.Currency
+	}
+	if details.BoxOffice != nil {
+		boxOffice, boxOfficeCurrency = &details.BoxOffice.Amount, &details.BoxOffice.Currency
+	}
+	_, err := db.db.Exec(
+		"INSERT INTO films_details (film_id, runtime, budget, budget_currency, box_office, box_office_currency) values ($1, $2, $3, $4, $5, $6) ON CONFLICT (film_id) DO UPDATE SET runtime = EXCLUDED.runtime, budget = EXCLUDED.budget, budget_currency = EXCLUDED.budget_currency, box_office = EXCLUDED.box_office, box_office_currency = EXCLUDED.box_office_currency;",
+		filmID,
+		details.Runtime,
+		budget,
+		budgetCurrency,
+		boxOffice,
+		boxOfficeCurrency,
+	)
+	return err
+}
+
+func (db *DBProvider) GetFilmReleases(filmID int) ([]*models.FilmRelease, error) {
+	rows, err := db.db.Query("SELECT country, release_date, certification FROM films_releases WHERE film_id = $1 ORDER BY release_date, country;", filmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.FilmRelease{}
+	for rows.Next() {
+		release := models.FilmRelease{ReleaseDate: &models.CustomDate{}}
+		err := rows.Scan(&release.Country, &release.ReleaseDate.Time, &release.Certification)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &release)
+	}
+	return res, nil
+}
+
+// SetFilmReleases replaces all regional releases of the film.
+func (db *DBProvider) SetFilmReleases(filmID int, releases []*models.FilmRelease) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("DELETE FROM films_releases WHERE film_id = $1;", filmID)
+	if err != nil {
+		return err
+	}
+	for _, release := range releases {
+		_, err = tx.Exec(
+			"INSERT INTO films_releases (film_id, country, release_date, certification) values ($1, $2, $3, $4);",
+			filmID,
+			release.Country,
+			release.ReleaseDate.Time,
+			release.Certification,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/ffdb42/vk_trainee_task/internal/constants"
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func (db *DBProvider) AddFranchise(franchise *models.Franchise) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO franchises (name, description) values ($1, $2) RETURNING id;",
+		franchise.Name,
+		franchise.Description,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (db *DBProvider) UpdateFranchise(franchise *models.Franchise) error {
+	_, err := db.db.Exec(
+		"UPDATE franchises SET name = $1, description = $2 WHERE id = $3;",
+		franchise.Name,
+		franchise.Description,
+		franchise.ID,
+	)
+	return err
+}
+
+func (db *DBProvider) GetFranchise(id int) (*models.Franchise, error) {
+	rows, err := db.db.Query("SELECT * FROM franchises WHERE id = $1;", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		res := models.Franchise{}
+		err := rows.Scan(&res.ID, &res.Name, &res.Description)
+		if err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, nil
+}
+
+func (db *DBProvider) GetFranchises() ([]*models.Franchise, error) {
+	rows, err := db.db.Query("SELECT * FROM franchises ORDER BY name;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Franchise{}
+	for rows.Next() {
+		franchise := models.Franchise{}
+		err := rows.Scan(&franchise.ID, &franchise.Name, &franchise.Description)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &franchise)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) DeleteFranchise(id int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM franchises WHERE id = $1;", id)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// SetFranchiseFilm adds the film to the franchise or moves it to the given
+// position if it is already a member.
+func (db *DBProvider) SetFranchiseFilm(franchiseID int, filmID int, position int) error {
+	_, err := db.db.Exec(
+		"INSERT INTO franchises_films (franchise_id, film_id, position) values ($1, $2, $3) ON CONFLICT (franchise_id, film_id) DO UPDATE SET position = EXCLUDED.position;",
+		franchiseID,
+		filmID,
+		position,
+	)
+	return err
+}
+
+func (db *DBProvider) DeleteFranchiseFilm(franchiseID int, filmID int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM franchises_films WHERE franchise_id = $1 AND film_id = $2;", franchiseID, filmID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+func (db *DBProvider) GetFranchiseFilms(franchiseID int, order string) ([]*models.FranchiseFilm, error) {
+	orderBy := "franchises_films.position, films.release_date"
+	if order == constants.FranchiseOrderReleaseDate {
+		orderBy = "films.release_date, franchises_films.position"
+	}
+	rows, err := db.db.Query(fmt.Sprintf("SELECT franchises_films.position, %s FROM films JOIN franchises_films ON films.id = franchises_films.film_id WHERE franchises_films.franchise_id = $1 AND films.deleted_at IS NULL ORDER BY %s;", filmColumns, orderBy), franchiseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.FranchiseFilm{}
+	for rows.Next() {
+		entry := models.FranchiseFilm{Film: &models.Film{ReleaseDate: &models.CustomDate{}}}
+		film := entry.Film
+		err := rows.Scan(&entry.Position, &film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &entry)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) GetFilmFranchises(filmID int) ([]*models.Franchise, error) {
+	rows, err := db.db.Query("SELECT franchises.* FROM franchises JOIN franchises_films ON franchises.id = franchises_films.franchise_id WHERE franchises_films.film_id = $1 ORDER BY franchises.name;", filmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Franchise{}
+	for rows.Next() {
+		franchise := models.Franchise{}
+		err := rows.Scan(&franchise.ID, &franchise.Name, &franchise.Description)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &franchise)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) AddFilmRelation(relation *models.FilmRelation) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO films_relations (film_id, related_film_id, type) values ($1, $2, $3) RETURNING id;",
+		relation.FilmID,
+		relation.RelatedFilmID,
+		relation.Type,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// DeleteFilmRelation deletes the relation only if the film is one of its ends.
+func (db *DBProvider) DeleteFilmRelation(filmID int, relationID int) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM films_relations WHERE id = $1 AND (film_id = $2 OR related_film_id = $2);", relationID, filmID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// GetFilmRelations returns relations of the film together with the film on
+// the other end. Outgoing relations answer "what is this film a sequel of",
+// incoming ones answer "what are the sequels of this film". Empty relType or
+// direction means no filtering.
+func (db *DBProvider) GetFilmRelations(filmID int, relType models.RelationType, direction models.RelationDirection) ([]*models.FilmRelationRespond, error) {
+	res := []*models.FilmRelationRespond{}
+	if direction == "" || direction == constants.DirectionOutgoing {
+		outgoing, err := db.getFilmRelations(
+			"SELECT films_relations.id, films_relations.type, "+filmColumns+" FROM films_relations JOIN films ON films.id = films_relations.related_film_id WHERE films_relations.film_id = $1 AND ($2 = '' OR films_relations.type = $2) AND films.deleted_at IS NULL ORDER BY films.release_date;",
+			filmID,
+			relType,
+			constants.DirectionOutgoing,
+		)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, outgoing...)
+	}
+	if direction == "" || direction == constants.DirectionIncoming {
+		incoming, err := db.getFilmRelations(
+			"SELECT films_relations.id, films_relations.type, "+filmColumns+" FROM films_relations JOIN films ON films.id = films_relations.film_id WHERE films_relations.related_film_id = $1 AND ($2 = '' OR films_relations.type = $2) AND films.deleted_at IS NULL ORDER BY films.release_date;",
+			filmID,
+			relType,
+			constants.DirectionIncoming,
+		)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, incoming...)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) getFilmRelations(query string, filmID int, relType models.RelationType, direction models.RelationDirection) ([]*models.FilmRelationRespond, error) {
+	rows, err := db.db.Query(query, filmID, string(relType))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.FilmRelationRespond{}
+	for rows.Next() {
+		relation := models.FilmRelationRespond{Direction: direction, Film: &models.Film{ReleaseDate: &models.CustomDate{}}}
+		film := relation.Film
+		err := rows.Scan(&relation.ID, &relation.Type, &film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &relation)
+	}
+	return res, nil
+}
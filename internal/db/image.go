@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// SetImage stores the image record and returns the previous one for the same
+// entity, if any, so that its blobs can be removed.
+func (db *DBProvider) SetImage(image *models.Image) (*models.Image, error) {
+	old, err := db.GetImage(image.Entity, image.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.db.Exec(
+		"INSERT INTO images (entity, entity_id, key_prefix, content_type, width, height) values ($1, $2, $3, $4, $5, $6) ON CONFLICT (entity, entity_id) DO UPDATE SET key_prefix = EXCLUDED.key_prefix, content_type = EXCLUDED.content_type, width = EXCLUDED.width, height = EXCLUDED.height, created_at = NOW();",
+		image.Entity,
+		image.EntityID,
+		image.KeyPrefix,
+		image.ContentType,
+		image.Width,
+		image.Height,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+func (db *DBProvider) GetImage(entity models.ImageEntity, entityID int) (*models.Image, error) {
+	rows, err := db.db.Query("SELECT * FROM images WHERE entity = $1 AND entity_id = $2;", entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		res := models.Image{}
+		err := rows.Scan(&res.ID, &res.Entity, &res.EntityID, &res.KeyPrefix, &res.ContentType, &res.Width, &res.Height, &res.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, nil
+}
+
+func (db *DBProvider) DeleteImage(entity models.ImageEntity, entityID int) (*models.Image, error) {
+	image, err := db.GetImage(entity, entityID)
+	if err != nil || image == nil {
+		return nil, err
+	}
+	_, err = db.db.Exec("DELETE FROM images WHERE id = $1;", image.ID)
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+// GetLoginAttempts returns failed logins of the keys that have them.
+func (db *DBProvider) GetLoginAttempts(keys []string) (map[string]*models.LoginAttempt, error) {
+	rows, err := db.db.Query("SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = ANY($1);", pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]*models.LoginAttempt{}
+	for rows.Next() {
+		attempt := models.LoginAttempt{}
+		var lockedUntil sql.NullTime
+		err := rows.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &lockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		attempt.LockedUntil = nullTime(lockedUntil)
+		res[attempt.Key] = &attempt
+	}
+	return res, nil
+}
+
+// AddLoginFailure counts a failed login for the key and returns the number of
+// failures. Failures older than resetBefore are forgotten.
+func (db *DBProvider) AddLoginFailure(key string, now time.Time, resetBefore time.Time) (int, error) {
+	failures := 0
+	err := db.db.QueryRow(
+		`INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = $2
+		RETURNING failures;`,
+		key,
+		now,
+		resetBefore,
+	).Scan(&failures)
+	return failures, err
+}
+
+func (db *DBProvider) LockLogin(key string, until time.Time) error {
+	_, err := db.db.Exec("UPDATE login_attempts SET locked_until = GREATEST(locked_until, $2) WHERE key = $1;", key, until)
+	return err
+}
+
+// ClearLoginAttempts forgets failures of the key and lifts its lock.
+func (db *DBProvider) ClearLoginAttempts(key string) (int64, error) {
+	return db.execCount("DELETE FROM login_attempts WHERE key = $1;", key)
+}
+
+// PurgeLoginAttempts removes keys without failures since before which are not
+// locked anymore.
+func (db *DBProvider) PurgeLoginAttempts(before time.Time, now time.Time) (int64, error) {
+	return db.execCount("DELETE FROM login_attempts WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $2);", before, now)
+}
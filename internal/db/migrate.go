@@ -0,0 +1,34 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// migrations bring databases created by earlier versions of sql/init.sql up
+// to date. sql/init.sql only runs on an empty data directory, so every
+// migration has to be idempotent: they all run on each start.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func (db *DBProvider) migrate() error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		_, err = db.db.Exec(string(query))
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+	}
+	return nil
+}
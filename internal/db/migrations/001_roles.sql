@@ -0,0 +1,50 @@
+-- Before roles were stored in the database users.role held "user" or
+-- "admin", with "user" granting read-only access that is now called "viewer".
+-- Databases created from the current sql/init.sql already have the roles and
+-- the constraint, so every statement is a no-op for them.
+
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(200) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('viewer', 'Просмотр каталога'),
+    ('editor', 'Добавление и редактирование фильмов, актеров, франшиз и наград без удаления'),
+    ('moderator', 'Редактирование, удаление, слияние актеров, откат ревизий, работа с корзиной и модерация предложений'),
+    ('admin', 'Полный доступ')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions (role, permission)
+SELECT role, unnest(permissions) FROM (VALUES
+    ('viewer', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit']),
+    ('editor', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write']),
+    ('moderator', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write',
+        'films:delete', 'actors:delete', 'actors:merge', 'revisions:revert', 'trash:manage', 'proposals:moderate']),
+    ('admin', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write',
+        'films:delete', 'actors:delete', 'actors:merge', 'revisions:revert', 'trash:manage', 'proposals:moderate',
+        'api_keys:manage', 'roles:manage', 'users:manage', 'metrics:read'])
+) AS defaults (role, permissions)
+ON CONFLICT DO NOTHING;
+
+UPDATE users SET role = 'viewer' WHERE role = 'user' OR role IS NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_fkey') THEN
+        ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50);
+        ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+        ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+        ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles (name) ON UPDATE CASCADE;
+    END IF;
+END;
+$$;
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (db *DBProvider) AddPasswordResetToken(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := db.db.Exec(
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3);",
+		userID,
+		tokenHash,
+		expiresAt,
+	)
+	return err
+}
+
+// HasRecentPasswordResetToken reports whether a reset token was issued to
+// the user after the given moment.
+func (db *DBProvider) HasRecentPasswordResetToken(userID int, since time.Time) (bool, error) {
+	exists := false
+	err := db.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM password_reset_tokens WHERE user_id = $1 AND created_at > $2);",
+		userID,
+		since,
+	).Scan(&exists)
+	return exists, err
+}
+
+// ResetPassword uses the reset token and sets the new password. All other
+// credentials of the user are invalidated: refresh tokens, API keys and other
+// reset tokens. It returns the owner of the token, or
+// ErrNotFound if the token is unknown, expired or already used.
+func (db *DBProvider) ResetPassword(tokenHash string, password string) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	userID := 0
+	err = tx.QueryRow(
+		"UPDATE password_reset_tokens SET used_at = NOW() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() RETURNING user_id;",
+		tokenHash,
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	err = setPassword(tx, userID, password)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;", userID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL;", userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// PurgePasswordResetTokens removes tokens expired before the given moment.
+func (db *DBProvider) PurgePasswordResetTokens(before time.Time) (int64, error) {
+	return db.execCount("DELETE FROM password_reset_tokens WHERE expires_at < $1;", before)
+}
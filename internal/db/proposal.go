@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+const proposalColumns = "id, entity, entity_id, action, payload, comment, status, author, created_at, reviewed_by, reviewed_at, reject_reason"
+
+func (db *DBProvider) AddProposal(proposal *models.Proposal) (int, error) {
+	id := 0
+	err := db.db.QueryRow(
+		"INSERT INTO proposals (entity, entity_id, action, payload, comment, author) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;",
+		proposal.Entity,
+		proposal.EntityID,
+		proposal.Action,
+		string(proposal.Payload),
+		proposal.Comment,
+		proposal.Author,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *DBProvider) GetProposal(id int) (*models.Proposal, error) {
+	proposals, err := db.getProposals("SELECT "+proposalColumns+" FROM proposals WHERE id = $1;", id)
+	if err != nil || len(proposals) == 0 {
+		return nil, err
+	}
+	return proposals[0], nil
+}
+
+// GetProposals returns proposals matching the filter, the oldest first so
+// that the queue is reviewed in order. Empty filter fields match anything.
+func (db *DBProvider) GetProposals(filter *models.ProposalsFilter) ([]*models.Proposal, error) {
+	return db.getProposals(
+		`SELECT `+proposalColumns+` FROM proposals
+		WHERE ($1 = '' OR author = $1)
+		AND ($2 = '' OR status = $2)
+		AND ($3 = '' OR entity = $3)
+		ORDER BY created_at, id;`,
+		filter.Author,
+		filter.Status,
+		filter.Entity,
+	)
+}
+
+// ReviewProposal sets the status of a pending proposal. It returns 0 if the
+// proposal does not exist or has already been reviewed.
+func (db *DBProvider) ReviewProposal(id int, status models.ProposalStatus, reviewer string, reason *string) (int64, error) {
+	return db.execCount(
+		"UPDATE proposals SET status = $2, reviewed_by = $3, reviewed_at = NOW(), reject_reason = $4 WHERE id = $1 AND status = 'pending';",
+		id,
+		status,
+		reviewer,
+		reason,
+	)
+}
+
+// ReopenProposal returns the proposal to the queue, for example when it could
+// not be applied.
+func (db *DBProvider) ReopenProposal(id int) error {
+	_, err := db.db.Exec("UPDATE proposals SET status = 'pending', reviewed_by = NULL, reviewed_at = NULL, reject_reason = NULL WHERE id = $1;", id)
+	return err
+}
+
+func (db *DBProvider) getProposals(query string, args ...any) ([]*models.Proposal, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Proposal{}
+	for rows.Next() {
+		proposal := models.Proposal{}
+		var entityID sql.NullInt64
+		var payload []byte
+		var comment, reviewedBy, rejectReason sql.NullString
+		var reviewedAt sql.NullTime
+		err := rows.Scan(
+			&proposal.ID,
+			&proposal.Entity,
+			&entityID,
+			&proposal.Action,
+			&payload,
+			&comment,
+			&proposal.Status,
+			&proposal.Author,
+			&proposal.CreatedAt,
+			&reviewedBy,
+			&reviewedAt,
+			&rejectReason,
+		)
+		if err != nil {
+			return nil, err
+		}
+		proposal.Payload = payload
+		if entityID.Valid {
+			id := int(entityID.Int64)
+			proposal.EntityID = &id
+		}
+		proposal.Comment = nullString(comment)
+		proposal.ReviewedBy = nullString(reviewedBy)
+		proposal.ReviewedAt = nullTime(reviewedAt)
+		proposal.RejectReason = nullString(rejectReason)
+		res = append(res, &proposal)
+	}
+	return res, nil
+}
+
+func nullString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
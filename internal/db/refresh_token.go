@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (db *DBProvider) AddRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := db.db.Exec(
+		"INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3);",
+		userID,
+		tokenHash,
+		expiresAt,
+	)
+	return err
+}
+
+// RotateRefreshToken revokes the active refresh token and stores the one
+// replacing it, so every refresh token can be used only once. It returns the
+// owner of the token, or ErrNotFound if the token is unknown, expired or
+// already revoked.
+func (db *DBProvider) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (int, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	userID := 0
+	err = tx.QueryRow(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW() RETURNING user_id;",
+		oldHash,
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3);",
+		userID,
+		newHash,
+		expiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+func (db *DBProvider) RevokeRefreshToken(tokenHash string) (int64, error) {
+	return db.execCount("UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL;", tokenHash)
+}
+
+// PurgeRefreshTokens removes tokens expired before the given moment.
+func (db *DBProvider) PurgeRefreshTokens(before time.Time) (int64, error) {
+	return db.execCount("DELETE FROM refresh_tokens WHERE expires_at < $1;", before)
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+const revisionColumns = "id, entity, entity_id, action, author, created_at, before, after"
+
+func (db *DBProvider) AddRevision(revision *models.Revision) error {
+	_, err := db.db.Exec(
+		"INSERT INTO revisions (entity, entity_id, action, author, before, after) values ($1, $2, $3, $4, $5, $6);",
+		revision.Entity,
+		revision.EntityID,
+		revision.Action,
+		revision.Author,
+		nullableJSON(revision.Before),
+		nullableJSON(revision.After),
+	)
+	return err
+}
+
+func (db *DBProvider) GetRevision(id int) (*models.Revision, error) {
+	revisions, err := db.getRevisions("SELECT "+revisionColumns+" FROM revisions WHERE id = $1;", id)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return revisions[0], nil
+}
+
+// GetFilmRevisions returns revisions of the film and of its cast links.
+func (db *DBProvider) GetFilmRevisions(filmID int) ([]*models.Revision, error) {
+	return db.getRevisions("SELECT "+revisionColumns+" FROM revisions WHERE entity IN ('film', 'cast') AND entity_id = $1 ORDER BY created_at, id;", filmID)
+}
+
+// GetActorRevisions returns revisions of the actor and of its cast links.
+func (db *DBProvider) GetActorRevisions(actorID int) ([]*models.Revision, error) {
+	return db.getRevisions("SELECT "+revisionColumns+" FROM revisions WHERE (entity = 'actor' AND entity_id = $1) OR (entity = 'cast' AND (COALESCE(after, before)->>'actor_id')::INTEGER = $1) ORDER BY created_at, id;", actorID)
+}
+
+// GetAuthorRevisions returns revisions made by the user.
+func (db *DBProvider) GetAuthorRevisions(author string) ([]*models.Revision, error) {
+	return db.getRevisions("SELECT "+revisionColumns+" FROM revisions WHERE author = $1 ORDER BY created_at, id;", author)
+}
+
+func (db *DBProvider) getRevisions(query string, args ...any) ([]*models.Revision, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Revision{}
+	for rows.Next() {
+		revision := models.Revision{}
+		var before, after []byte
+		err := rows.Scan(&revision.ID, &revision.Entity, &revision.EntityID, &revision.Action, &revision.Author, &revision.CreatedAt, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		revision.Before, revision.After = before, after
+		res = append(res, &revision)
+	}
+	return res, nil
+}
+
+// nullableJSON stores empty snapshots as NULL instead of invalid JSON.
+func nullableJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// GetStatesAsOf returns the first revision of every film or actor made after
+// asOf. Its Before is the state at asOf, or nil if the entity did not exist
+// then. Entities without such a revision have not changed since asOf.
+func (db *DBProvider) GetStatesAsOf(entity models.RevisionEntity, asOf time.Time) ([]*models.Revision, error) {
+	return db.getRevisions("SELECT DISTINCT ON (entity_id) "+revisionColumns+" FROM revisions WHERE entity = $1 AND created_at > $2 ORDER BY entity_id, created_at, id;", entity, asOf)
+}
+
+// GetStateAsOf returns the first revision of the film or the actor made after
+// asOf, or nil if it has not changed since then.
+func (db *DBProvider) GetStateAsOf(entity models.RevisionEntity, id int, asOf time.Time) (*models.Revision, error) {
+	revisions, err := db.getRevisions("SELECT "+revisionColumns+" FROM revisions WHERE entity = $1 AND entity_id = $2 AND created_at > $3 ORDER BY created_at, id LIMIT 1;", entity, id, asOf)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return revisions[0], nil
+}
+
+// GetCastAsOf returns all cast links that existed at asOf.
+func (db *DBProvider) GetCastAsOf(asOf time.Time) ([]*models.CastLink, error) {
+	return db.castAsOf("TRUE", asOf)
+}
+
+// GetFilmCastAsOf returns cast links of the film that existed at asOf.
+func (db *DBProvider) GetFilmCastAsOf(filmID int, asOf time.Time) ([]*models.CastLink, error) {
+	return db.castAsOf("film_id = $2", asOf, filmID)
+}
+
+// GetActorCastAsOf returns cast links of the actor that existed at asOf.
+func (db *DBProvider) GetActorCastAsOf(actorID int, asOf time.Time) ([]*models.CastLink, error) {
+	return db.castAsOf("actor_id = $2", asOf, actorID)
+}
+
+// castAsOf takes a link from the first cast revision made after asOf, and
+// from films_actors if the link has not changed since then.
+func (db *DBProvider) castAsOf(condition string, asOf time.Time, args ...any) ([]*models.CastLink, error) {
+	rows, err := db.db.Query(fmt.Sprintf(
+		`WITH later AS (
+			SELECT DISTINCT ON (entity_id, COALESCE(after, before)->>'actor_id')
+				entity_id AS film_id, (COALESCE(after, before)->>'actor_id')::INTEGER AS actor_id, before
+			FROM revisions WHERE entity = 'cast' AND created_at > $1
+			ORDER BY entity_id, COALESCE(after, before)->>'actor_id', created_at, id
+		)
+		SELECT film_id, actor_id FROM (
+			SELECT film_id, actor_id FROM later WHERE before IS NOT NULL
+			UNION
+			SELECT film_id, actor_id FROM films_actors
+			WHERE NOT EXISTS (SELECT 1 FROM later WHERE later.film_id = films_actors.film_id AND later.actor_id = films_actors.actor_id)
+		) AS links WHERE %s ORDER BY film_id, actor_id;`,
+		condition,
+	), append([]any{asOf}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.CastLink{}
+	for rows.Next() {
+		link := models.CastLink{}
+		err := rows.Scan(&link.FilmID, &link.ActorID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &link)
+	}
+	return res, nil
+}
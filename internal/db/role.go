@@ -0,0 +1,108 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+// GetRoles returns all roles with their permissions ordered by name.
+func (db *DBProvider) GetRoles() ([]*models.Role, error) {
+	rows, err := db.db.Query(
+		`SELECT roles.name, roles.description, COALESCE(array_agg(roles_permissions.permission ORDER BY roles_permissions.permission) FILTER (WHERE roles_permissions.permission IS NOT NULL), '{}')
+		FROM roles LEFT JOIN roles_permissions ON roles_permissions.role = roles.name
+		GROUP BY roles.name ORDER BY roles.name;`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Role{}
+	for rows.Next() {
+		role := models.Role{}
+		var description string
+		err := rows.Scan(&role.Name, &description, pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, err
+		}
+		role.Description = &description
+		res = append(res, &role)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) GetRole(name string) (*models.Role, error) {
+	roles, err := db.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *DBProvider) AddRole(role *models.Role) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT INTO roles (name, description) VALUES ($1, $2);", role.Name, role.Description)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO roles_permissions (role, permission) SELECT $1, unnest($2::VARCHAR[]);", role.Name, pq.Array(role.Permissions))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRole replaces the description and the permissions of the role.
+func (db *DBProvider) UpdateRole(role *models.Role) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE roles SET description = $2 WHERE name = $1;", role.Name, role.Description)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM roles_permissions WHERE role = $1;", role.Name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO roles_permissions (role, permission) SELECT $1, unnest($2::VARCHAR[]);", role.Name, pq.Array(role.Permissions))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRole removes the role. Roles assigned to users can not be deleted,
+// that results in a foreign key violation.
+func (db *DBProvider) DeleteRole(name string) (int64, error) {
+	return db.execCount("DELETE FROM roles WHERE name = $1;", name)
+}
+
+// SetUserRole assigns the role and revokes refresh and access tokens and
+// sessions issued with the previous one.
+func (db *DBProvider) SetUserRole(userID int, role string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE users SET role = $2, credentials_changed_at = date_trunc('second', NOW()) WHERE id = $1;", userID, role)
+	if err != nil {
+		return err
+	}
+	err = revokeUserTokens(tx, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+const sessionColumns = "sessions.id, users.name, sessions.ip, sessions.user_agent, sessions.created_at, sessions.last_seen_at, sessions.expires_at, users.id, users.role, sessions.csrf_hash"
+
+// AddSession stores the session and fills its id and creation time.
+func (db *DBProvider) AddSession(session *models.Session, tokenHash string) error {
+	err := db.db.QueryRow(
+		"INSERT INTO sessions (user_id, token_hash, csrf_hash, ip, user_agent, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at;",
+		session.UserID,
+		tokenHash,
+		session.CSRFHash,
+		session.IP,
+		session.UserAgent,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt)
+	session.LastSeenAt = session.CreatedAt
+	return err
+}
+
+// UseSession returns the active session with the given hash and extends its
+// idle timeout, nil means that there is no such session or it has timed out.
+// Sessions idle since before idleSince are timed out.
+func (db *DBProvider) UseSession(tokenHash string, idleSince time.Time) (*models.Session, error) {
+	sessions, err := db.getSessions(
+		"UPDATE sessions SET last_seen_at = NOW() FROM users WHERE users.id = sessions.user_id AND sessions.token_hash = $1 AND sessions.revoked_at IS NULL AND sessions.expires_at > NOW() AND sessions.last_seen_at > $2 RETURNING "+sessionColumns+";",
+		tokenHash,
+		idleSince,
+	)
+	if err != nil || len(sessions) == 0 {
+		return nil, err
+	}
+	return sessions[0], nil
+}
+
+// GetSessions returns active sessions of the user, the most recently used
+// first.
+func (db *DBProvider) GetSessions(userID int, idleSince time.Time) ([]*models.Session, error) {
+	return db.getSessions(
+		"SELECT "+sessionColumns+" FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.user_id = $1 AND sessions.revoked_at IS NULL AND sessions.expires_at > NOW() AND sessions.last_seen_at > $2 ORDER BY sessions.last_seen_at DESC;",
+		userID,
+		idleSince,
+	)
+}
+
+// RevokeSession revokes the session if it belongs to the user.
+func (db *DBProvider) RevokeSession(id int, userID int) (int64, error) {
+	return db.execCount("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;", id, userID)
+}
+
+// RevokeSessions revokes all sessions of the user except the one with exceptID.
+func (db *DBProvider) RevokeSessions(userID int, exceptID int) (int64, error) {
+	return db.execCount("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;", userID, exceptID)
+}
+
+// PurgeSessions removes sessions expired before the given moment or idle
+// since before idleBefore.
+func (db *DBProvider) PurgeSessions(idleBefore time.Time, before time.Time) (int64, error) {
+	return db.execCount("DELETE FROM sessions WHERE last_seen_at < $1 OR expires_at < $2;", idleBefore, before)
+}
+
+func (db *DBProvider) getSessions(query string, args ...any) ([]*models.Session, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Session{}
+	for rows.Next() {
+		session := models.Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.Login,
+			&session.IP,
+			&session.UserAgent,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+			&session.UserID,
+			&session.Role,
+			&session.CSRFHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &session)
+	}
+	return res, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+	"github.com/lib/pq"
+)
+
+func (db *DBProvider) SetFilmTranslation(translation *models.FilmTranslation) error {
+	_, err := db.db.Exec(
+		"INSERT INTO films_translations (film_id, lang, name, description) values ($1, $2, $3, $4) ON CONFLICT (film_id, lang) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description;",
+		translation.FilmID,
+		translation.Lang,
+		translation.Name,
+		translation.Description,
+	)
+	return err
+}
+
+func (db *DBProvider) DeleteFilmTranslation(filmID int, lang string) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM films_translations WHERE film_id = $1 AND lang = $2;", filmID, lang)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// GetFilmsTranslations returns translations of the films into the given
+// languages. Empty langs means all languages.
+func (db *DBProvider) GetFilmsTranslations(filmIDs []int, langs []string) ([]*models.FilmTranslation, error) {
+	rows, err := db.db.Query(
+		"SELECT film_id, lang, name, description FROM films_translations WHERE film_id = ANY($1) AND (cardinality($2::text[]) = 0 OR lang = ANY($2)) ORDER BY film_id, lang;",
+		pq.Array(filmIDs),
+		pq.Array(langs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.FilmTranslation{}
+	for rows.Next() {
+		translation := models.FilmTranslation{}
+		err := rows.Scan(&translation.FilmID, &translation.Lang, &translation.Name, &translation.Description)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &translation)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) SetActorTranslation(translation *models.ActorTranslation) error {
+	_, err := db.db.Exec(
+		"INSERT INTO actors_translations (actor_id, lang, first_name, last_name) values ($1, $2, $3, $4) ON CONFLICT (actor_id, lang) DO UPDATE SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name;",
+		translation.ActorID,
+		translation.Lang,
+		translation.FirstName,
+		translation.LastName,
+	)
+	return err
+}
+
+func (db *DBProvider) DeleteActorTranslation(actorID int, lang string) (int64, error) {
+	res, err := db.db.Exec("DELETE FROM actors_translations WHERE actor_id = $1 AND lang = $2;", actorID, lang)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// GetActorsTranslations returns translations of the actors' names into the
+// given languages. Empty langs means all languages.
+func (db *DBProvider) GetActorsTranslations(actorIDs []int, langs []string) ([]*models.ActorTranslation, error) {
+	rows, err := db.db.Query(
+		"SELECT actor_id, lang, first_name, last_name FROM actors_translations WHERE actor_id = ANY($1) AND (cardinality($2::text[]) = 0 OR lang = ANY($2)) ORDER BY actor_id, lang;",
+		pq.Array(actorIDs),
+		pq.Array(langs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ActorTranslation{}
+	for rows.Next() {
+		translation := models.ActorTranslation{}
+		err := rows.Scan(&translation.ActorID, &translation.Lang, &translation.FirstName, &translation.LastName)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &translation)
+	}
+	return res, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+func (db *DBProvider) GetDeletedFilms() ([]*models.DeletedFilm, error) {
+	rows, err := db.db.Query("SELECT " + filmColumns + ", deleted_at, deleted_by FROM films WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.DeletedFilm{}
+	for rows.Next() {
+		film := models.Film{ReleaseDate: &models.CustomDate{}}
+		deleted := models.DeletedFilm{Film: &film}
+		err := rows.Scan(&film.ID, &film.Name, &film.Description, &film.ReleaseDate.Time, &film.Rating, &deleted.DeletedAt, &deleted.DeletedBy)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &deleted)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) GetDeletedActors() ([]*models.DeletedActor, error) {
+	rows, err := db.db.Query("SELECT " + actorColumns + ", deleted_at, deleted_by FROM actors WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.DeletedActor{}
+	for rows.Next() {
+		actor := models.Actor{Birthdate: &models.CustomDate{}}
+		deleted := models.DeletedActor{Actor: &actor}
+		err := rows.Scan(&actor.ID, &actor.FirstName, &actor.LastName, &actor.Sex, &actor.Birthdate.Time, &deleted.DeletedAt, &deleted.DeletedBy)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &deleted)
+	}
+	return res, nil
+}
+
+// FilmExists reports whether the film exists and is not in the trash.
+func (db *DBProvider) FilmExists(id int) (bool, error) {
+	exists := false
+	err := db.db.QueryRow("SELECT EXISTS (SELECT 1 FROM films WHERE id = $1 AND deleted_at IS NULL);", id).Scan(&exists)
+	return exists, err
+}
+
+// ActorExists reports whether the actor exists and is not in the trash.
+func (db *DBProvider) ActorExists(id int) (bool, error) {
+	exists := false
+	err := db.db.QueryRow("SELECT EXISTS (SELECT 1 FROM actors WHERE id = $1 AND deleted_at IS NULL);", id).Scan(&exists)
+	return exists, err
+}
+
+// RestoreFilm takes the film out of the trash. Its cast was kept on deletion
+// and is restored with it.
+func (db *DBProvider) RestoreFilm(id int) (int64, error) {
+	return db.execCount("UPDATE films SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL;", id)
+}
+
+// RestoreActor takes the actor out of the trash together with the credits.
+func (db *DBProvider) RestoreActor(id int) (int64, error) {
+	return db.execCount("UPDATE actors SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL;", id)
+}
+
+// PurgeFilm permanently deletes the film from the trash.
+func (db *DBProvider) PurgeFilm(id int) (int64, error) {
+	return db.execCount("DELETE FROM films WHERE id = $1 AND deleted_at IS NOT NULL;", id)
+}
+
+// PurgeActor permanently deletes the actor from the trash.
+func (db *DBProvider) PurgeActor(id int) (int64, error) {
+	return db.execCount("DELETE FROM actors WHERE id = $1 AND deleted_at IS NOT NULL;", id)
+}
+
+// PurgeDeleted permanently deletes films and actors that were moved to the
+// trash before the given time and returns their ids.
+func (db *DBProvider) PurgeDeleted(before time.Time) ([]int, []int, error) {
+	films, err := db.purgeDeleted("films", before)
+	if err != nil {
+		return nil, nil, err
+	}
+	actors, err := db.purgeDeleted("actors", before)
+	if err != nil {
+		return nil, nil, err
+	}
+	return films, actors, nil
+}
+
+func (db *DBProvider) purgeDeleted(table string, before time.Time) ([]int, error) {
+	rows, err := db.db.Query(fmt.Sprintf("DELETE FROM %s WHERE deleted_at < $1 RETURNING id;", table), before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []int{}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) execCount(query string, args ...any) (int64, error) {
+	res, err := db.db.Exec(query, args...)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import "database/sql"
+
+// InTx runs fn with a provider bound to a transaction and commits it if fn
+// succeeds. Methods of the provider that need a transaction themselves run in
+// the same one. Called inside InTx, fn joins the running transaction.
+func (db *DBProvider) InTx(fn func(tx *DBProvider) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	tx, err := db.pool.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(&DBProvider{db: tx, pool: db.pool, tx: tx})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// txScope is the transaction of a single provider method. Inside InTx it is
+// the running transaction, which is committed or rolled back by its owner.
+type txScope struct {
+	*sql.Tx
+	owned bool
+}
+
+func (db *DBProvider) begin() (*txScope, error) {
+	if db.tx != nil {
+		return &txScope{Tx: db.tx}, nil
+	}
+	tx, err := db.pool.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &txScope{Tx: tx, owned: true}, nil
+}
+
+func (tx *txScope) Commit() error {
+	if !tx.owned {
+		return nil
+	}
+	return tx.Tx.Commit()
+}
+
+func (tx *txScope) Rollback() error {
+	if !tx.owned {
+		return nil
+	}
+	return tx.Tx.Rollback()
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// User columns along with failed logins of the account joined as
+// userInfoJoin.
+const (
+	userInfoColumns = "users.id, users.name, users.display_name, users.role, users.created_at, users.disabled_at, users.disabled_by, users.must_reset_password, COALESCE(login_attempts.failures, 0), login_attempts.locked_until"
+	userInfoJoin    = "users LEFT JOIN login_attempts ON login_attempts.key = 'user:' || users.name"
+)
+
+// GetUsers returns users matching the filter ordered by login. Search is a
+// case insensitive fragment of the login.
+func (db *DBProvider) GetUsers(filter *models.UsersFilter) ([]*models.UserInfo, error) {
+	rows, err := db.db.Query(
+		`SELECT `+userInfoColumns+` FROM `+userInfoJoin+`
+		WHERE ($1 = '' OR users.name ILIKE '%' || $1 || '%')
+		AND ($2 = '' OR users.role = $2)
+		AND ($3::BOOLEAN IS NULL OR (users.disabled_at IS NOT NULL) = $3)
+		ORDER BY users.name;`,
+		filter.Search,
+		filter.Role,
+		filter.Disabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.UserInfo{}
+	for rows.Next() {
+		user, err := scanUserInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, user)
+	}
+	return res, nil
+}
+
+func (db *DBProvider) GetUserInfo(name string) (*models.UserInfo, error) {
+	rows, err := db.db.Query("SELECT "+userInfoColumns+" FROM "+userInfoJoin+" WHERE users.name = $1;", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return scanUserInfo(rows)
+	}
+	return nil, nil
+}
+
+func scanUserInfo(rows interface{ Scan(...any) error }) (*models.UserInfo, error) {
+	user := models.UserInfo{}
+	var disabledAt, lockedUntil sql.NullTime
+	var displayName, disabledBy sql.NullString
+	err := rows.Scan(&user.ID, &user.Name, &displayName, &user.Role, &user.CreatedAt, &disabledAt, &disabledBy, &user.MustResetPassword, &user.FailedLogins, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	user.DisabledAt = nullTime(disabledAt)
+	user.LockedUntil = nullTime(lockedUntil)
+	if displayName.Valid {
+		user.DisplayName = &displayName.String
+	}
+	if disabledBy.Valid {
+		user.DisabledBy = &disabledBy.String
+	}
+	return &user, nil
+}
+
+// SetDisplayName sets the display name of the user, nil removes it.
+func (db *DBProvider) SetDisplayName(userID int, displayName *string) error {
+	_, err := db.db.Exec("UPDATE users SET display_name = $2 WHERE id = $1;", userID, displayName)
+	return err
+}
+
+// SetUserDisabled disables or enables the account. Refresh tokens and
+// sessions of a disabled user are revoked.
+func (db *DBProvider) SetUserDisabled(userID int, disabled bool, disabledBy string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if disabled {
+		_, err = tx.Exec("UPDATE users SET disabled_at = NOW(), disabled_by = $2 WHERE id = $1 AND disabled_at IS NULL;", userID, disabledBy)
+	} else {
+		_, err = tx.Exec("UPDATE users SET disabled_at = NULL, disabled_by = NULL WHERE id = $1;", userID)
+	}
+	if err != nil {
+		return err
+	}
+	if disabled {
+		err = revokeUserTokens(tx, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ForcePasswordReset requires the user to change the password before using
+// the API again and revokes refresh tokens and sessions of the user.
+func (db *DBProvider) ForcePasswordReset(userID int) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE users SET must_reset_password = TRUE WHERE id = $1;", userID)
+	if err != nil {
+		return err
+	}
+	err = revokeUserTokens(tx, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRestrictedUsers returns disabled users, users who have to reset the
+// password and users whose credentials changed after changedAfter by their
+// ids. Users deleted after changedAfter are returned with the deletion time
+// as CredentialsChangedAt.
+func (db *DBProvider) GetRestrictedUsers(changedAfter time.Time) (map[int]*models.User, error) {
+	rows, err := db.db.Query(
+		"SELECT "+userColumns+" FROM users WHERE disabled_at IS NOT NULL OR must_reset_password OR credentials_changed_at > $1 "+
+			"UNION ALL SELECT user_id, name, '', '', FALSE, FALSE, deleted_at FROM deleted_users WHERE deleted_at > $1;",
+		changedAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[int]*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res[user.ID] = user
+	}
+	return res, nil
+}
+
+// SetUserPassword stores the new password hash, lifts the password reset
+// requirement and revokes refresh and access tokens and sessions issued
+// before the change.
+func (db *DBProvider) SetUserPassword(userID int, password string) error {
+	tx, err := db.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = setPassword(tx, userID, password)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func setPassword(tx querier, userID int, password string) error {
+	_, err := tx.Exec("UPDATE users SET password = $2, must_reset_password = FALSE, credentials_changed_at = date_trunc('second', NOW()) WHERE id = $1;", userID, password)
+	if err != nil {
+		return err
+	}
+	return revokeUserTokens(tx, userID)
+}
+
+// revokeUserTokens revokes refresh tokens and sessions of the user.
+func revokeUserTokens(tx querier, userID int) error {
+	_, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;", userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;", userID)
+	return err
+}
+
+// UpdatePasswordHash replaces the hash of the same password made with other
+// parameters. Nothing happens if the password was changed meanwhile.
+func (db *DBProvider) UpdatePasswordHash(userID int, oldHash string, newHash string) error {
+	_, err := db.db.Exec("UPDATE users SET password = $3 WHERE id = $1 AND password = $2;", userID, oldHash, newHash)
+	return err
+}
+
+// DeleteUser removes the account along with its refresh tokens, sessions and
+// API keys. The deletion is remembered, so that access tokens issued to the
+// user are rejected until they expire.
+func (db *DBProvider) DeleteUser(userID int) (int64, error) {
+	tx, err := db.begin()
+	if err != nil {
+		return -1, err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT INTO deleted_users (user_id, name) SELECT id, name FROM users WHERE id = $1;", userID)
+	if err != nil {
+		return -1, err
+	}
+	res, err := tx.Exec("DELETE FROM users WHERE id = $1;", userID)
+	if err != nil {
+		return -1, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return -1, err
+	}
+	return count, tx.Commit()
+}
+
+// PurgeDeletedUsers forgets users deleted before the given time, access
+// tokens issued to them have expired by then.
+func (db *DBProvider) PurgeDeletedUsers(before time.Time) (int64, error) {
+	return db.execCount("DELETE FROM deleted_users WHERE deleted_at < $1;", before)
+}
@@ -1,11 +1,15 @@
 package models
 
 type Actor struct {
-	ID        int         `json:"id"`
-	FirstName *string     `json:"first_name"`
-	LastName  *string     `json:"last_name"`
-	Sex       *string     `json:"sex"`
-	Birthdate *CustomDate `json:"birthdate"`
+	ID          int           `json:"id"`
+	FirstName   *string       `json:"first_name"`
+	LastName    *string       `json:"last_name"`
+	Sex         *string       `json:"sex"`
+	Birthdate   *CustomDate   `json:"birthdate"`
+	DisplayName *string       `json:"display_name,omitempty"`
+	Age         *int          `json:"age,omitempty"`
+	Details     *ActorDetails `json:"details,omitempty"`
+	ExternalIDs []*ExternalID `json:"external_ids,omitempty"`
 }
 
 func (a Actor) CopyWith(from *Actor) *Actor {
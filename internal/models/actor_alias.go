@@ -0,0 +1,26 @@
+package models
+
+type ActorAlias struct {
+	ID      int       `json:"id"`
+	ActorID int       `json:"-"`
+	Name    string    `json:"name"`
+	Type    AliasType `json:"type"`
+	Lang    *string   `json:"lang"`
+	Primary bool      `json:"primary"`
+}
+
+func (a ActorAlias) CopyWith(from *ActorAliasPost) *ActorAlias {
+	if from.Name != nil {
+		a.Name = *from.Name
+	}
+	if from.Type != nil {
+		a.Type = *from.Type
+	}
+	if from.Lang != nil {
+		a.Lang = from.Lang
+	}
+	if from.Primary != nil {
+		a.Primary = *from.Primary
+	}
+	return &a
+}
@@ -0,0 +1,30 @@
+package models
+
+type ActorDetails struct {
+	DeathDate     *CustomDate `json:"death_date"`
+	Birthplace    *string     `json:"birthplace"`
+	Nationalities []string    `json:"nationalities"`
+	Biography     *string     `json:"biography"`
+}
+
+func (d ActorDetails) CopyWith(from *ActorDetails) *ActorDetails {
+	if from.DeathDate != nil {
+		d.DeathDate = from.DeathDate
+	}
+	if from.Birthplace != nil {
+		d.Birthplace = from.Birthplace
+	}
+	if from.Nationalities != nil {
+		d.Nationalities = from.Nationalities
+	}
+	if from.Biography != nil {
+		d.Biography = from.Biography
+	}
+	return &d
+}
+
+type ActorFilm struct {
+	*Film
+	AgeAtRelease *int `json:"age_at_release,omitempty"`
+	Posthumous   bool `json:"posthumous"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	Login      string     `json:"login"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	// UserID and Role identify the owner when the key is used to
+	// authenticate.
+	UserID int    `json:"-"`
+	Role   string `json:"-"`
+}
+
+// APIKeyPost is the request for a new key. The key belongs to the current
+// user unless another login is provided.
+type APIKeyPost struct {
+	Login      *string    `json:"login"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreated holds the secret key, it is shown only once.
+type APIKeyCreated struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
+
+type GetAPIKeys struct {
+	APIKeys []*APIKey `json:"api_keys"`
+}
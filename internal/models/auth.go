@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type TokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	Name         string `json:"login"`
+	Password     string `json:"password"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenRespond struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokeRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PasswordResetRequest struct {
+	Name string `json:"login"`
+}
+
+type PasswordResetConfirm struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+type Award struct {
+	ID       int     `json:"id"`
+	Ceremony *string `json:"ceremony"`
+	Year     *int    `json:"year"`
+	Category *string `json:"category"`
+}
+
+func (a Award) CopyWith(from *Award) *Award {
+	if from.Ceremony != nil {
+		a.Ceremony = from.Ceremony
+	}
+	if from.Year != nil {
+		a.Year = from.Year
+	}
+	if from.Category != nil {
+		a.Category = from.Category
+	}
+	return &a
+}
+
+// Nomination links an award either to a film or, when ActorID is set, to
+// the actor's credit in that film.
+type Nomination struct {
+	ID      int  `json:"id"`
+	AwardID int  `json:"award_id"`
+	FilmID  int  `json:"film_id"`
+	ActorID *int `json:"actor_id"`
+	Won     bool `json:"won"`
+}
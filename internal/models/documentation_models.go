@@ -18,15 +18,34 @@ type FilmsSearch struct {
 }
 
 type ActorPost struct {
-	FirstName *string `json:"first_name"`
-	LastName  *string `json:"last_name"`
-	Sex       *string `json:"sex"`
-	Birthdate *string `json:"birthdate"`
+	FirstName   *string          `json:"first_name"`
+	LastName    *string          `json:"last_name"`
+	Sex         *string          `json:"sex"`
+	Birthdate   *string          `json:"birthdate"`
+	Details     *ActorDetailsDoc `json:"details"`
+	ExternalIDs []*ExternalID    `json:"external_ids"`
+}
+
+type ActorDetailsDoc struct {
+	DeathDate     *string  `json:"death_date"`
+	Birthplace    *string  `json:"birthplace"`
+	Nationalities []string `json:"nationalities"`
+	Biography     *string  `json:"biography"`
 }
 
 type FilmPostDoc struct {
-	Film       FilmDoc `json:"film"`
-	ActorsList []int   `json:"actors_ids"`
+	Film             FilmDoc           `json:"film"`
+	ActorsList       []int             `json:"actors_ids"`
+	PosthumousActors []int             `json:"posthumous_actors_ids"`
+	Details          *FilmDetails      `json:"details"`
+	Releases         []*FilmReleaseDoc `json:"releases"`
+	ExternalIDs      []*ExternalID     `json:"external_ids"`
+}
+
+type FilmReleaseDoc struct {
+	Country       string  `json:"country"`
+	ReleaseDate   string  `json:"release_date"`
+	Certification *string `json:"certification"`
 }
 
 type FilmDoc struct {
@@ -35,3 +54,70 @@ type FilmDoc struct {
 	ReleaseDate *string `json:"release_date"`
 	Rating      *int    `json:"rating"`
 }
+
+type GetFranchises struct {
+	Franchises []*Franchise `json:"franchises"`
+}
+
+type FranchisePost struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type GetFilmRelations struct {
+	Relations []*FilmRelationRespond `json:"relations"`
+}
+
+type GetAwards struct {
+	Awards []*Award `json:"awards"`
+}
+
+type AwardPost struct {
+	Ceremony *string `json:"ceremony"`
+	Year     *int    `json:"year"`
+	Category *string `json:"category"`
+}
+
+type GetNominations struct {
+	Nominations []*NominationRespond `json:"nominations"`
+}
+
+type GetCeremonies struct {
+	Ceremonies []*CeremonyRespond `json:"ceremonies"`
+}
+
+type CeremonyYear struct {
+	Ceremony string          `json:"ceremony"`
+	Year     int             `json:"year"`
+	Awards   []*AwardRespond `json:"awards"`
+}
+
+type FilmTranslationPut struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type ActorTranslationPut struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+}
+
+type GetFilmTranslations struct {
+	Translations []*FilmTranslation `json:"translations"`
+}
+
+type GetActorTranslations struct {
+	Translations []*ActorTranslation `json:"translations"`
+}
+
+type GetActorAliases struct {
+	Aliases []*ActorAlias `json:"aliases"`
+}
+
+type GetDuplicates struct {
+	Matches []*DuplicateMatch `json:"matches"`
+}
+
+type GetRevisions struct {
+	Revisions []*Revision `json:"revisions"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type ExternalID struct {
+	Source ExternalSource `json:"source"`
+	Value  string         `json:"value"`
+}
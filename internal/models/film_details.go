@@ -0,0 +1,31 @@
+package models
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type FilmDetails struct {
+	Runtime   *int   `json:"runtime"`
+	Budget    *Money `json:"budget"`
+	BoxOffice *Money `json:"box_office"`
+}
+
+func (d FilmDetails) CopyWith(from *FilmDetails) *FilmDetails {
+	if from.Runtime != nil {
+		d.Runtime = from.Runtime
+	}
+	if from.Budget != nil {
+		d.Budget = from.Budget
+	}
+	if from.BoxOffice != nil {
+		d.BoxOffice = from.BoxOffice
+	}
+	return &d
+}
+
+type FilmRelease struct {
+	Country       string      `json:"country"`
+	ReleaseDate   *CustomDate `json:"release_date"`
+	Certification *string     `json:"certification"`
+}
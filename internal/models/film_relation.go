@@ -0,0 +1,10 @@
+package models
+
+// FilmRelation is a directed link: FilmID is a Type of RelatedFilmID,
+// e.g. FilmID is a sequel of RelatedFilmID.
+type FilmRelation struct {
+	ID            int          `json:"id"`
+	FilmID        int          `json:"film_id"`
+	RelatedFilmID int          `json:"related_film_id"`
+	Type          RelationType `json:"type"`
+}
@@ -0,0 +1,22 @@
+package models
+
+type Franchise struct {
+	ID          int     `json:"id"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (f Franchise) CopyWith(from *Franchise) *Franchise {
+	if from.Name != nil {
+		f.Name = from.Name
+	}
+	if from.Description != nil {
+		f.Description = from.Description
+	}
+	return &f
+}
+
+type FranchiseFilm struct {
+	Position int   `json:"position"`
+	Film     *Film `json:"film"`
+}
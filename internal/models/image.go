@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Image is an uploaded film poster or actor photo. Blobs are stored under
+// KeyPrefix: the original upload and one file per thumbnail size.
+type Image struct {
+	ID          int
+	Entity      ImageEntity
+	EntityID    int
+	KeyPrefix   string
+	ContentType string
+	Width       int
+	Height      int
+	CreatedAt   time.Time
+}
+
+type ImageURLs struct {
+	Original   string            `json:"original"`
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
@@ -2,3 +2,12 @@ package models
 
 type SortOrder string
 type SortBy string
+type RelationType string
+type RelationDirection string
+type ImageEntity string
+type AliasType string
+type ExternalSource string
+type RevisionEntity string
+type RevisionAction string
+type ProposalStatus string
+type TrashEntity string
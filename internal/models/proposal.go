@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Proposal is a change of a film, an actor or a film cast submitted by a
+// contributor. Payload is the body of the create or update request that
+// applies the change once a moderator approves it.
+type Proposal struct {
+	ID           int             `json:"id"`
+	Entity       RevisionEntity  `json:"entity"`
+	EntityID     *int            `json:"entity_id"`
+	Action       RevisionAction  `json:"action"`
+	Payload      json.RawMessage `json:"payload" swaggertype:"object"`
+	Comment      *string         `json:"comment"`
+	Status       ProposalStatus  `json:"status"`
+	Author       string          `json:"author"`
+	CreatedAt    time.Time       `json:"created_at"`
+	ReviewedBy   *string         `json:"reviewed_by"`
+	ReviewedAt   *time.Time      `json:"reviewed_at"`
+	RejectReason *string         `json:"reject_reason"`
+}
+
+// ProposalPost is a new proposal. Payload has the format of POST /film/ or
+// PUT /film/{id} for films, POST /actor/ or PUT /actor/{id} for actors and
+// CastProposal for casts.
+type ProposalPost struct {
+	Entity   RevisionEntity  `json:"entity"`
+	EntityID *int            `json:"entity_id"`
+	Action   RevisionAction  `json:"action"`
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+	Comment  *string         `json:"comment"`
+}
+
+// CastProposal adds actors to the cast of the film and removes them from it.
+type CastProposal struct {
+	ActorsList   []int `json:"actors_ids"`
+	RemoveActors []int `json:"remove_actors_ids"`
+}
+
+type ProposalReject struct {
+	Reason string `json:"reason"`
+}
+
+type ProposalsFilter struct {
+	Author string
+	Status ProposalStatus
+	Entity RevisionEntity
+}
+
+type GetProposals struct {
+	Proposals []*Proposal `json:"proposals"`
+}
+
+// ProposalDiff compares the current record with the record after the
+// proposal is applied. Current is null for proposed creates.
+type ProposalDiff struct {
+	Proposal *Proposal      `json:"proposal"`
+	Current  any            `json:"current"`
+	Proposed any            `json:"proposed"`
+	Changes  []*FieldChange `json:"changes"`
+}
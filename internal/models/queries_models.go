@@ -1,8 +1,12 @@
 package models
 
 type FilmPost struct {
-	Film       Film  `json:"film"`
-	ActorsList []int `json:"actors_ids"`
+	Film             Film           `json:"film"`
+	ActorsList       []int          `json:"actors_ids"`
+	PosthumousActors []int          `json:"posthumous_actors_ids"`
+	Details          *FilmDetails   `json:"details"`
+	Releases         []*FilmRelease `json:"releases"`
+	ExternalIDs      []*ExternalID  `json:"external_ids"`
 }
 
 type FilmPut struct {
@@ -11,11 +15,92 @@ type FilmPut struct {
 }
 
 type ActorRespond struct {
-	Actor *Actor  `json:"actor"`
-	Films []*Film `json:"films"`
+	Actor *Actor       `json:"actor"`
+	Films []*ActorFilm `json:"films"`
+	Photo *ImageURLs   `json:"photo,omitempty"`
 }
 
 type FilmRespond struct {
-	Film   *Film    `json:"film"`
-	Actors []*Actor `json:"actors"`
+	Film        *Film                  `json:"film"`
+	Actors      []*Actor               `json:"actors"`
+	Franchises  []*Franchise           `json:"franchises,omitempty"`
+	Relations   []*FilmRelationRespond `json:"relations,omitempty"`
+	Poster      *ImageURLs             `json:"poster,omitempty"`
+	Details     *FilmDetails           `json:"details,omitempty"`
+	Releases    []*FilmRelease         `json:"releases,omitempty"`
+	ExternalIDs []*ExternalID          `json:"external_ids,omitempty"`
+}
+
+type FranchiseRespond struct {
+	Franchise *Franchise       `json:"franchise"`
+	Films     []*FranchiseFilm `json:"films"`
+}
+
+type FranchiseFilmPost struct {
+	FilmID   int `json:"film_id"`
+	Position int `json:"position"`
+}
+
+type FilmRelationPost struct {
+	RelatedFilmID int          `json:"related_film_id"`
+	Type          RelationType `json:"type"`
+}
+
+type FilmRelationRespond struct {
+	ID        int               `json:"id"`
+	Type      RelationType      `json:"type"`
+	Direction RelationDirection `json:"direction"`
+	Film      *Film             `json:"film"`
+}
+
+type FilmsFilter struct {
+	AwardWonID int
+	RuntimeMin int
+	RuntimeMax int
+	ReleasedIn string
+}
+
+type NominationPost struct {
+	FilmID  int  `json:"film_id"`
+	ActorID *int `json:"actor_id"`
+	Won     bool `json:"won"`
+}
+
+type NominationPut struct {
+	Won *bool `json:"won"`
+}
+
+type NominationRespond struct {
+	ID    int    `json:"id"`
+	Won   bool   `json:"won"`
+	Award *Award `json:"award,omitempty"`
+	Film  *Film  `json:"film,omitempty"`
+	Actor *Actor `json:"actor,omitempty"`
+}
+
+type AwardRespond struct {
+	Award       *Award               `json:"award"`
+	Nominations []*NominationRespond `json:"nominations"`
+}
+
+type CeremonyRespond struct {
+	Ceremony string `json:"ceremony"`
+	Years    []int  `json:"years"`
+}
+
+type ActorAliasPost struct {
+	Name    *string    `json:"name"`
+	Type    *AliasType `json:"type"`
+	Lang    *string    `json:"lang"`
+	Primary *bool      `json:"primary"`
+}
+
+type ActorMergePost struct {
+	DuplicateID int `json:"duplicate_id"`
+}
+
+type DuplicateMatch struct {
+	Score float64 `json:"score"`
+	Actor *Actor  `json:"actor,omitempty"`
+	Film  *Film   `json:"film,omitempty"`
 }
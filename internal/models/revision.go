@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision is an immutable record of a change of a film, an actor or a cast
+// link. Before and After hold snapshots of the entity, Before is null for
+// created entities and After is null for deleted ones.
+type Revision struct {
+	ID        int             `json:"id"`
+	Entity    RevisionEntity  `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Action    RevisionAction  `json:"action"`
+	Author    string          `json:"author"`
+	CreatedAt time.Time       `json:"created_at"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+}
+
+// State returns the snapshot of the entity after the revision, or the last
+// one before deletion.
+func (r *Revision) State() json.RawMessage {
+	if r.After != nil {
+		return r.After
+	}
+	return r.Before
+}
+
+// CastLink is the snapshot of a cast revision, its EntityID is the film id.
+type CastLink struct {
+	FilmID  int `json:"film_id"`
+	ActorID int `json:"actor_id"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type RevisionDiff struct {
+	From    int            `json:"from"`
+	To      int            `json:"to"`
+	Changes []*FieldChange `json:"changes"`
+}
+
+// FilmSnapshot is the snapshot of a film revision: the film with the
+// details, releases, external ids and posthumous credits saved along with it.
+// They are null in snapshots recorded before they were included.
+type FilmSnapshot struct {
+	Film
+	Details          *FilmDetails   `json:"details"`
+	Releases         []*FilmRelease `json:"releases"`
+	ExternalIDs      []*ExternalID  `json:"external_ids"`
+	PosthumousActors []int          `json:"posthumous_actors_ids"`
+}
+
+// ActorSnapshot is the snapshot of an actor revision: the actor with the
+// details and external ids saved along with it. They are null in snapshots
+// recorded before they were included.
+type ActorSnapshot struct {
+	Actor
+	Details     *ActorDetails `json:"details"`
+	ExternalIDs []*ExternalID `json:"external_ids"`
+}
@@ -0,0 +1,23 @@
+package models
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r Role) CopyWith(from *Role) *Role {
+	if from.Description != nil {
+		r.Description = from.Description
+	}
+	if from.Permissions != nil {
+		r.Permissions = from.Permissions
+	}
+	return &r
+}
+
+type GetRoles struct {
+	Roles []*Role `json:"roles"`
+	// Permissions lists all permissions that can be granted to roles.
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         int       `json:"id"`
+	Login      string    `json:"login"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+	// UserID, Role and CSRFHash are used to authenticate requests made
+	// with the session.
+	UserID   int    `json:"-"`
+	Role     string `json:"-"`
+	CSRFHash string `json:"-"`
+}
+
+type GetSessions struct {
+	Sessions []*Session `json:"sessions"`
+}
+
+type LoginRequest struct {
+	Name     string `json:"login"`
+	Password string `json:"password"`
+}
+
+// LoginRespond holds the CSRF token of the new session. It is also set in a
+// cookie readable by scripts, so it survives page reloads.
+type LoginRespond struct {
+	CSRFToken             string    `json:"csrf_token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
+}
@@ -0,0 +1,15 @@
+package models
+
+type FilmTranslation struct {
+	FilmID      int     `json:"-"`
+	Lang        string  `json:"lang"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type ActorTranslation struct {
+	ActorID   int     `json:"-"`
+	Lang      string  `json:"lang"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type DeletedFilm struct {
+	Film      *Film     `json:"film"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *string   `json:"deleted_by"`
+}
+
+type DeletedActor struct {
+	Actor     *Actor    `json:"actor"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *string   `json:"deleted_by"`
+}
+
+type TrashRespond struct {
+	Films  []*DeletedFilm  `json:"films"`
+	Actors []*DeletedActor `json:"actors"`
+}
@@ -1,8 +1,74 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       int    `json:"-"`
-	Name     string `json:"login"`
+	ID                int    `json:"-"`
+	Name              string `json:"login"`
+	Password          string `json:"password"`
+	Role              string `json:"-"`
+	Disabled          bool   `json:"-"`
+	MustResetPassword bool   `json:"-"`
+	// CredentialsChangedAt is when the password was last changed or reset,
+	// access tokens issued before it are rejected.
+	CredentialsChangedAt *time.Time `json:"-"`
+}
+
+// UserInfo is the account as shown to administrators.
+type UserInfo struct {
+	ID                int        `json:"id"`
+	Name              string     `json:"login"`
+	DisplayName       *string    `json:"display_name"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DisabledAt        *time.Time `json:"disabled_at"`
+	DisabledBy        *string    `json:"disabled_by"`
+	MustResetPassword bool       `json:"must_reset_password"`
+	// FailedLogins is the number of recent failed logins, LockedUntil is set
+	// if the account has been locked after them.
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+type UsersFilter struct {
+	Search   string
+	Role     string
+	Disabled *bool
+}
+
+type GetUsers struct {
+	Users []*UserInfo `json:"users"`
+}
+
+type UserRolePut struct {
+	Role string `json:"role"`
+}
+
+// Profile is the account as shown to its owner.
+type Profile struct {
+	Name              string    `json:"login"`
+	DisplayName       *string   `json:"display_name"`
+	Role              string    `json:"role"`
+	Permissions       []string  `json:"permissions"`
+	CreatedAt         time.Time `json:"created_at"`
+	MustResetPassword bool      `json:"must_reset_password"`
+}
+
+// ProfilePut changes the display name, an empty one removes it.
+type ProfilePut struct {
+	DisplayName *string `json:"display_name"`
+}
+
+// AccountDelete confirms deletion of the own account with the password.
+type AccountDelete struct {
 	Password string `json:"password"`
-	Role     string `json:"-"`
+}
+
+// PersonalDataExport holds everything stored about the user.
+type PersonalDataExport struct {
+	ExportedAt time.Time   `json:"exported_at"`
+	Account    *Profile    `json:"account"`
+	Sessions   []*Session  `json:"sessions"`
+	APIKeys    []*APIKey   `json:"api_keys"`
+	Revisions  []*Revision `json:"revisions"`
 }
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ffdb42/vk_trainee_task/internal/models"
+)
+
+// Notifier delivers messages such as password reset links to users.
+type Notifier interface {
+	Notify(user *models.User, subject string, body string) error
+}
+
+// WriterNotifier writes messages to a writer instead of delivering them. It
+// is meant for local environments where the messages are read from stdout or
+// a file.
+type WriterNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterNotifier(w io.Writer) *WriterNotifier {
+	return &WriterNotifier{w: w}
+}
+
+// NewFileNotifier appends messages to the file at path.
+func NewFileNotifier(path string) (*WriterNotifier, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open notifications file: %w", err)
+	}
+	return NewWriterNotifier(file), nil
+}
+
+func (n *WriterNotifier) Notify(user *models.User, subject string, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.w, "--- %v\nTo: %v\nSubject: %v\n\n%v\n", time.Now().Format(time.RFC3339), user.Name, subject, body)
+	return err
+}
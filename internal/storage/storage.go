@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files under slash-separated keys.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// URL returns the address the blob can be downloaded from by clients.
+	URL(key string) string
+}
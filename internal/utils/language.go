@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// NormalizeLanguage lowercases the language tag and reports whether it is a
+// valid "xx" or "xx-yy" tag.
+func NormalizeLanguage(lang string) (string, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	lang = strings.ReplaceAll(lang, "_", "-")
+	return lang, languageTag.MatchString(lang)
+}
+
+// ParseAcceptLanguage returns languages from the Accept-Language header value
+// ordered by their q-values. Invalid tags, "*" and q=0 entries are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	langs := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang, ok := NormalizeLanguage(fields[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{lang: lang, q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	res := []string{}
+	for _, v := range langs {
+		res = append(res, v.lang)
+	}
+	return res
+}
+
+func HasCyrillic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+var translitTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+}
+
+// Transliterate converts cyrillic letters to latin ones, other characters
+// are kept as is.
+func Transliterate(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+		latin, ok := translitTable[lower]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if lower != r && latin != "" {
+			// Keep "SHCH" in all caps words and "Shch" otherwise.
+			nextUpper := i+1 < len(runes) && unicode.IsUpper(runes[i+1])
+			if nextUpper {
+				latin = strings.ToUpper(latin)
+			} else {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+		}
+		b.WriteString(latin)
+	}
+	return b.String()
+}
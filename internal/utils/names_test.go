@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"lower case", "Leonardo DiCaprio", "leonardo dicaprio"},
+		{"extra spaces", "  Tom   Hanks ", "tom hanks"},
+		{"punctuation", "Robert Downey, Jr.", "robert downey jr"},
+		{"hyphen", "Jean-Paul Belmondo", "jean paul belmondo"},
+		{"diacritics", "Penélope Cruz", "penelope cruz"},
+		{"ligature", "Œdipe", "oedipe"},
+		{"eszett", "Gießen", "giessen"},
+		{"cyrillic", "Никита Михалков", "nikita mikhalkov"},
+		{"yo", "Алёна Бабенко", "alena babenko"},
+		{"hard sign", "Подъезд", "podezd"},
+		{"digits", "Терминатор 2", "terminator 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeName(tt.in); got != tt.want {
+				t.Errorf("NormalizeName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want float64
+	}{
+		{"both empty", "", "", 0},
+		{"one empty", "tom", "", 0},
+		{"equal", "tom hanks", "tom hanks", 1},
+		{"one substitution", "tom hanks", "tom hankz", 1 - 1.0/9},
+		{"one insertion", "tom hanks", "tom hankss", 1 - 1.0/10},
+		{"one deletion", "tom hanks", "tom hank", 1 - 1.0/9},
+		{"different", "abc", "xyz", 0},
+		{"multibyte runes", "ёж", "еж", 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if reverse := NameSimilarity(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+				t.Errorf("NameSimilarity(%q, %q) = %v, not symmetric with %v", tt.b, tt.a, reverse, got)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func ParseID(uri string) (int, error) {
@@ -19,3 +20,31 @@ func ParseID(uri string) (int, error) {
 	}
 	return id, nil
 }
+
+func PathSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+func ParseSegmentID(segments []string, idx int) (int, error) {
+	if len(segments) <= idx {
+		return -1, nil
+	}
+	id, err := strconv.Atoi(segments[idx])
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id")
+	}
+	return id, nil
+}
+
+// Age returns the number of full years between birth and at.
+func Age(birth, at time.Time) int {
+	age := at.Year() - birth.Year()
+	if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
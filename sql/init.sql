@@ -1,8 +1,47 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(200) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('viewer', 'Просмотр каталога'),
+    ('editor', 'Добавление и редактирование фильмов, актеров, франшиз и наград без удаления'),
+    ('moderator', 'Редактирование, удаление, слияние актеров, откат ревизий, работа с корзиной и модерация предложений'),
+    ('admin', 'Полный доступ')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions (role, permission)
+SELECT role, unnest(permissions) FROM (VALUES
+    ('viewer', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit']),
+    ('editor', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write']),
+    ('moderator', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write',
+        'films:delete', 'actors:delete', 'actors:merge', 'revisions:revert', 'trash:manage', 'proposals:moderate']),
+    ('admin', ARRAY['films:read', 'actors:read', 'franchises:read', 'awards:read', 'proposals:submit',
+        'films:create', 'films:update', 'actors:create', 'actors:update', 'franchises:write', 'awards:write',
+        'films:delete', 'actors:delete', 'actors:merge', 'revisions:revert', 'trash:manage', 'proposals:moderate',
+        'api_keys:manage', 'roles:manage', 'users:manage', 'metrics:read'])
+) AS defaults (role, permissions)
+ON CONFLICT DO NOTHING;
+
 CREATE TABLE IF NOT EXISTS users (
   id SERIAL PRIMARY KEY,
   name VARCHAR(100) UNIQUE,
-  role VARCHAR(100),
-  password VARCHAR(100)
+  display_name VARCHAR(100),
+  role VARCHAR(50) NOT NULL DEFAULT 'viewer' REFERENCES roles (name) ON UPDATE CASCADE,
+  password VARCHAR(255),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  disabled_at TIMESTAMP,
+  disabled_by VARCHAR(100),
+  must_reset_password BOOLEAN NOT NULL DEFAULT FALSE,
+  credentials_changed_at TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS films (
@@ -10,7 +49,9 @@ CREATE TABLE IF NOT EXISTS films (
     name VARCHAR(150) NOT NULL,
     description VARCHAR(1500),
     release_date DATE NOT NULL,
-    rating integer DEFAULT 0 CHECK (rating >= 0 AND rating <= 10)
+    rating integer DEFAULT 0 CHECK (rating >= 0 AND rating <= 10),
+    deleted_at TIMESTAMP,
+    deleted_by VARCHAR(100)
 );
 
 CREATE TABLE IF NOT EXISTS actors (
@@ -18,12 +59,254 @@ CREATE TABLE IF NOT EXISTS actors (
     first_name VARCHAR(20),
     last_name VARCHAR(20),
     sex VARCHAR(1),
-    birthdate DATE NOT NULL
+    birthdate DATE NOT NULL,
+    deleted_at TIMESTAMP,
+    deleted_by VARCHAR(100)
 );
 
 CREATE TABLE IF NOT EXISTS films_actors(
     id SERIAL PRIMARY KEY,
     film_id INTEGER REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
     actor_id INTEGER REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    posthumous BOOLEAN NOT NULL DEFAULT FALSE,
     UNIQUE (film_id, actor_id)
 );
+
+CREATE TABLE IF NOT EXISTS franchises (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(150) NOT NULL UNIQUE,
+    description VARCHAR(1500)
+);
+
+CREATE TABLE IF NOT EXISTS franchises_films (
+    id SERIAL PRIMARY KEY,
+    franchise_id INTEGER REFERENCES franchises (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    film_id INTEGER REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (franchise_id, film_id)
+);
+
+CREATE TABLE IF NOT EXISTS films_relations (
+    id SERIAL PRIMARY KEY,
+    film_id INTEGER REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    related_film_id INTEGER REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('sequel', 'prequel', 'remake', 'spin_off')),
+    CHECK (film_id <> related_film_id),
+    UNIQUE (film_id, related_film_id, type)
+);
+
+CREATE TABLE IF NOT EXISTS awards (
+    id SERIAL PRIMARY KEY,
+    ceremony VARCHAR(150) NOT NULL,
+    year INTEGER NOT NULL,
+    category VARCHAR(150) NOT NULL,
+    UNIQUE (ceremony, year, category)
+);
+
+CREATE TABLE IF NOT EXISTS nominations (
+    id SERIAL PRIMARY KEY,
+    award_id INTEGER NOT NULL REFERENCES awards (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    film_id INTEGER NOT NULL REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    actor_id INTEGER,
+    won BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (film_id, actor_id) REFERENCES films_actors (film_id, actor_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE NULLS NOT DISTINCT (award_id, film_id, actor_id)
+);
+
+CREATE TABLE IF NOT EXISTS images (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(10) NOT NULL CHECK (entity IN ('film', 'actor')),
+    entity_id INTEGER NOT NULL,
+    key_prefix VARCHAR(200) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (entity, entity_id)
+);
+
+CREATE TABLE IF NOT EXISTS films_translations (
+    id SERIAL PRIMARY KEY,
+    film_id INTEGER NOT NULL REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    lang VARCHAR(12) NOT NULL,
+    name VARCHAR(150),
+    description VARCHAR(1500),
+    UNIQUE (film_id, lang)
+);
+
+CREATE TABLE IF NOT EXISTS actors_translations (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    lang VARCHAR(12) NOT NULL,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    UNIQUE (actor_id, lang)
+);
+
+CREATE TABLE IF NOT EXISTS actors_aliases (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('stage', 'birth', 'maiden', 'married', 'transliteration', 'other')),
+    lang VARCHAR(12),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (actor_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS actors_aliases_primary ON actors_aliases (actor_id) WHERE is_primary;
+
+CREATE TABLE IF NOT EXISTS films_details (
+    film_id INTEGER PRIMARY KEY REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    runtime INTEGER CHECK (runtime > 0),
+    budget BIGINT CHECK (budget >= 0),
+    budget_currency CHAR(3),
+    box_office BIGINT CHECK (box_office >= 0),
+    box_office_currency CHAR(3),
+    CHECK ((budget IS NULL) = (budget_currency IS NULL)),
+    CHECK ((box_office IS NULL) = (box_office_currency IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS films_releases (
+    id SERIAL PRIMARY KEY,
+    film_id INTEGER NOT NULL REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    country CHAR(2) NOT NULL,
+    release_date DATE NOT NULL,
+    certification VARCHAR(10),
+    UNIQUE (film_id, country)
+);
+
+CREATE TABLE IF NOT EXISTS actors_details (
+    actor_id INTEGER PRIMARY KEY REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    death_date DATE,
+    birthplace VARCHAR(200),
+    nationalities CHAR(2)[] NOT NULL DEFAULT '{}',
+    biography VARCHAR(5000)
+);
+
+CREATE TABLE IF NOT EXISTS actors_redirects (
+    old_id INTEGER PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS films_external_ids (
+    id SERIAL PRIMARY KEY,
+    film_id INTEGER NOT NULL REFERENCES films (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    value VARCHAR(50) NOT NULL,
+    UNIQUE (source, value)
+);
+
+CREATE TABLE IF NOT EXISTS actors_external_ids (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES actors (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    value VARCHAR(50) NOT NULL,
+    UNIQUE (source, value)
+);
+
+CREATE TABLE IF NOT EXISTS revisions (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(10) NOT NULL CHECK (entity IN ('film', 'actor', 'cast')),
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert')),
+    author VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    before JSONB,
+    after JSONB
+);
+
+CREATE INDEX IF NOT EXISTS revisions_entity ON revisions (entity, entity_id, created_at);
+
+CREATE OR REPLACE FUNCTION revisions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER revisions_immutable BEFORE UPDATE OR DELETE ON revisions
+    FOR EACH ROW EXECUTE FUNCTION revisions_immutable();
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(12) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(30)[] NOT NULL,
+    allowed_ips VARCHAR(50)[] NOT NULL DEFAULT '{}',
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- Failed logins per account ("user:<login>") and per client address
+-- ("ip:<address>"), shared by all app instances.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(150) PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- Browser sessions. The cookie holds the session token, non-GET requests have
+-- to repeat the CSRF token in a header. Only hashes of both are stored.
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    csrf_hash CHAR(64) NOT NULL,
+    ip VARCHAR(50) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+-- Recently deleted accounts. Access tokens can not be revoked, so they are
+-- rejected by the user id until they expire.
+CREATE TABLE IF NOT EXISTS deleted_users (
+    user_id INTEGER PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Changes proposed by contributors. Payload is the body of the request that
+-- applies the change once a moderator approves it.
+CREATE TABLE IF NOT EXISTS proposals (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(10) NOT NULL CHECK (entity IN ('film', 'actor', 'cast')),
+    entity_id INTEGER,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update')),
+    payload JSONB NOT NULL,
+    comment VARCHAR(500),
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    author VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reviewed_by VARCHAR(100),
+    reviewed_at TIMESTAMP,
+    reject_reason VARCHAR(500)
+);
+
+CREATE INDEX IF NOT EXISTS proposals_status ON proposals (status, created_at);
+CREATE INDEX IF NOT EXISTS proposals_author ON proposals (author, created_at);